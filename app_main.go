@@ -44,6 +44,8 @@ var (
 	TheOptionsGamePlayScreen *BaseOptionsScreen
 	TheOptionsControlsScreen *BaseOptionsScreen
 	TheGameScreen            *GameScreen
+	TheResultScreen          *ResultScreen
+	TheCalibrationScreen     *CalibrationScreen
 
 	NextScreen Screen
 
@@ -152,6 +154,31 @@ func RunApplication() {
 		DisplayAlert("failed to load settings")
 	}
 
+	UpdateSoundPack()
+
+	// remove old cached audio
+	if err := EvictAudioCache(AudioCacheLimit()); err != nil {
+		ErrorLogger.Println(err)
+	}
+
+	// load bookmarks
+	if err := LoadBookMarks(); err != nil {
+		ErrorLogger.Println(err)
+		DisplayAlert("failed to load bookmarks")
+	}
+
+	// load play history
+	if err := LoadPlayHistory(); err != nil {
+		ErrorLogger.Println(err)
+		DisplayAlert("failed to load play history")
+	}
+
+	// load song overrides
+	if err := LoadSongOverrides(); err != nil {
+		ErrorLogger.Println(err)
+		DisplayAlert("failed to load song overrides")
+	}
+
 	// create screens
 	TheGameScreen = NewGameScreen()
 	TheSelectScreen = NewSelectScreen()
@@ -159,6 +186,8 @@ func RunApplication() {
 	TheOptionsMainScreen = NewOptionsMainScreen()
 	TheOptionsGamePlayScreen = NewOptionsGamePlayScreen()
 	TheOptionsControlsScreen = NewOptionsControlsScreen()
+	TheResultScreen = NewResultScreen()
+	TheCalibrationScreen = NewCalibrationScreen()
 
 	screensToFree := []Screen{
 		TheGameScreen,
@@ -166,6 +195,8 @@ func RunApplication() {
 		TheOptionsMainScreen,
 		TheOptionsGamePlayScreen,
 		TheOptionsControlsScreen,
+		TheResultScreen,
+		TheCalibrationScreen,
 	}
 
 	// queue freeing
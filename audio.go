@@ -2,9 +2,11 @@ package fnf
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -23,11 +25,13 @@ var TheContext *oto.Context
 
 var TheAudioManager struct {
 	globalVolume float64
+	mixer        [MixerTrackSize]MixerChannel
 	players      []*VaryingSpeedPlayer
 }
 
 func InitAudio() error {
 	TheAudioManager.globalVolume = 1.0
+	TheAudioManager.mixer = DefaultOptions.Mixer
 
 	contextOp := oto.NewContextOptions{
 		SampleRate:   SampleRate,
@@ -52,8 +56,9 @@ func InitAudio() error {
 func UpdateAudio() {
 	volume := Clamp(TheOptions.Volume, 0, 1)
 
-	if volume != TheAudioManager.globalVolume {
+	if volume != TheAudioManager.globalVolume || TheOptions.Mixer != TheAudioManager.mixer {
 		TheAudioManager.globalVolume = volume
+		TheAudioManager.mixer = TheOptions.Mixer
 
 		for _, p := range TheAudioManager.players {
 			p.SetVolume(p.Volume())
@@ -87,6 +92,14 @@ func NewAudioDeocoder(rawFile []byte, fileType string) (AudioDecoder, error) {
 		} else {
 			return decoder, nil
 		}
+	} else if strings.HasSuffix(strings.ToLower(fileType), "flac") {
+		if decoder, err := decodeFlac(bReader); err != nil {
+			return nil, err
+		} else {
+			return decoder, nil
+		}
+	} else if strings.HasSuffix(strings.ToLower(fileType), "opus") {
+		return decodeOpus(rawFile)
 	} else {
 		return nil, fmt.Errorf("can't decode audio format %v", fileType)
 	}
@@ -100,6 +113,11 @@ type VaryingSpeedPlayer struct {
 	padEnd   time.Duration
 
 	volume float64
+	muted  bool
+
+	mixerTrack MixerTrack
+
+	preservePitch bool
 
 	isPlaying bool
 }
@@ -109,6 +127,7 @@ func NewVaryingSpeedPlayer(padStart, padEnd time.Duration) *VaryingSpeedPlayer {
 	vp.padStart = padStart
 	vp.padEnd = padEnd
 	vp.volume = 1.0
+	vp.mixerTrack = MixerTrackNone
 
 	TheAudioManager.players = append(TheAudioManager.players, vp)
 
@@ -123,15 +142,7 @@ func (vp *VaryingSpeedPlayer) IsReady() bool {
 //
 // If your audio bytes are already decoded, just pass in bytes.
 func (vp *VaryingSpeedPlayer) loadAudioImpl(audioBytes []byte, isAudioDecoded bool, fileType string, decodeAudioInBackground bool) error {
-	if vp.player != nil {
-		vp.player.Close()
-		vp.player = nil
-	}
-
-	if vp.stream != nil {
-		vp.stream.QuitBackgroundDecoding()
-		vp.stream = nil
-	}
+	vp.unload()
 
 	// NOTE : this isn't a seperate function because I have a strong feeling that
 	// this is not an exact inverse to ByteLengthToTimeDuration
@@ -161,6 +172,24 @@ func (vp *VaryingSpeedPlayer) loadAudioImpl(audioBytes []byte, isAudioDecoded bo
 		stream = NewVaryingSpeedStreamFromDecodedAudio(audioBytes, padStartBytes, padEndBytes)
 	}
 
+	vp.setStream(stream)
+
+	return nil
+}
+
+func (vp *VaryingSpeedPlayer) unload() {
+	if vp.player != nil {
+		vp.player.Close()
+		vp.player = nil
+	}
+
+	if vp.stream != nil {
+		vp.stream.QuitBackgroundDecoding()
+		vp.stream = nil
+	}
+}
+
+func (vp *VaryingSpeedPlayer) setStream(stream *VaryingSpeedStream) {
 	player := TheContext.NewPlayer(stream)
 
 	// we need the ability to change the playback speed in real time
@@ -175,8 +204,7 @@ func (vp *VaryingSpeedPlayer) loadAudioImpl(audioBytes []byte, isAudioDecoded bo
 	vp.stream = stream
 
 	vp.SetVolume(vp.Volume())
-
-	return nil
+	vp.SetPreservePitch(vp.PreservePitch())
 }
 
 func (vp *VaryingSpeedPlayer) LoadAudio(rawFile []byte, fileType string, decodeAudioInBackground bool) error {
@@ -191,6 +219,14 @@ func (vp *VaryingSpeedPlayer) LoadDecodedAudio(decodedAudio []byte) {
 	vp.loadAudioImpl(decodedAudio, true, "", false)
 }
 
+// Loads a stream that only plays clicks at given beats, silent everywhere else.
+func (vp *VaryingSpeedPlayer) LoadClicks(beats []ClickBeat, click, accent []byte, duration time.Duration) {
+	vp.unload()
+
+	length := clickBytePosition(duration)
+	vp.setStream(newClickStream(newClickTrack(beats, click, accent), length))
+}
+
 // TODO : Position and SetPosition is fucked
 //
 //	if you do something like
@@ -262,8 +298,13 @@ func (vp *VaryingSpeedPlayer) SetVolume(volume float64) {
 
 	vp.volume = volume
 
+	if vp.muted {
+		volume = 0
+	}
+
 	if vp.IsReady() {
-		vp.player.SetVolume(TheAudioManager.globalVolume * volume)
+		vp.player.SetVolume(
+			TheAudioManager.globalVolume * mixerVolume(TheAudioManager.mixer, vp.mixerTrack) * volume)
 	}
 }
 
@@ -271,6 +312,26 @@ func (vp *VaryingSpeedPlayer) Volume() float64 {
 	return vp.volume
 }
 
+// Silences player without changing its volume, like muting a mixer track.
+func (vp *VaryingSpeedPlayer) SetMuted(muted bool) {
+	if vp.muted == muted {
+		return
+	}
+
+	vp.muted = muted
+	vp.SetVolume(vp.Volume())
+}
+
+func (vp *VaryingSpeedPlayer) IsMuted() bool {
+	return vp.muted
+}
+
+// Sets which mixer track player's volume is controlled by.
+func (vp *VaryingSpeedPlayer) SetMixerTrack(track MixerTrack) {
+	vp.mixerTrack = track
+	vp.SetVolume(vp.Volume())
+}
+
 func (vp *VaryingSpeedPlayer) Speed() float64 {
 	return vp.stream.Speed()
 }
@@ -288,6 +349,19 @@ func (vp *VaryingSpeedPlayer) SetSpeed(speed float64) {
 	vp.stream.SetSpeed(speed)
 }
 
+// If true, pitch stays the same when speed changes.
+func (vp *VaryingSpeedPlayer) SetPreservePitch(preservePitch bool) {
+	vp.preservePitch = preservePitch
+
+	if vp.IsReady() {
+		vp.stream.SetPreservePitch(preservePitch)
+	}
+}
+
+func (vp *VaryingSpeedPlayer) PreservePitch() bool {
+	return vp.preservePitch
+}
+
 func (vp *VaryingSpeedPlayer) AudioDuration() time.Duration {
 	if !vp.IsReady() {
 		return 0
@@ -322,6 +396,23 @@ func (vp *VaryingSpeedPlayer) QuitBackgroundDecoding() {
 	}
 }
 
+func (vp *VaryingSpeedPlayer) IsDecodingDone() bool {
+	if !vp.IsReady() {
+		return true
+	}
+	return vp.stream.IsDecodingDone()
+}
+
+// Returns error that stopped background decoding, nil if there wasn't one.
+//
+// Audio that couldn't be decoded is played as silence.
+func (vp *VaryingSpeedPlayer) DecodingError() error {
+	if !vp.IsReady() {
+		return nil
+	}
+	return vp.stream.DecodingError()
+}
+
 type VaryingSpeedStream struct {
 	io.ReadSeeker
 
@@ -335,12 +426,37 @@ type VaryingSpeedStream struct {
 	buffer       []byte
 	bytePosition int64
 
+	// position between bytePosition and next sample (0 <= fracPosition < 1)
+	fracPosition float64
+
+	preservePitch bool
+	stretcher     timeStretcher
+
+	// low pass filter for current speed, only used when audio is sped up
+	filter      *resampleFilter
+	filterSpeed float64
+
 	usingBgDecoding  bool
-	bgDecoderQueue   chan byte
-	bgDecoderQuit    bool
+	bgDecoderCancel  context.CancelFunc
 	bgDecoderMu      sync.Mutex
+	bgDecoderDone    bool
+	bgDecoderErr     error
 	decodedBytesSize int64
 
+	// decodedBytesSize when Read was called
+	// bytes past this might not be decoded yet
+	readableSize int64
+
+	// not nil if audio is decoded as it's played
+	streamer *audioStreamer
+
+	// key of the decoded audio in cache, empty if we don't cache it
+	cacheKey   string
+	cacheLimit int64
+
+	// not nil if stream only plays clicks
+	clicks *clickTrack
+
 	mu sync.Mutex
 }
 
@@ -363,6 +479,39 @@ func NewVaryingSpeedStream(
 
 	var err error
 
+	if TheOptions.StreamAudio {
+		FnfLogger.Println("streaming audio")
+		if err = vs.startStreaming(rawFile, fileType); err == nil {
+			return vs, nil
+		}
+
+		if errors.Is(err, errUndeterminedAudioLength) {
+			FnfLogger.Println("couldn't get known audio length, can't stream the audio")
+		} else if errors.Is(err, errStreamingUnsupported) {
+			ErrorLogger.Printf("can't stream the audio: %v", err)
+			DisplayAlert("can't stream the song, loading the whole audio instead")
+		} else {
+			return nil, err
+		}
+	}
+
+	// NOTE : we don't use the cache while streaming
+	// since cache would give us the whole decoded audio which is what streaming avoids
+
+	if limit := AudioCacheLimit(); limit > 0 {
+		vs.cacheKey = AudioCacheKey(rawFile)
+		vs.cacheLimit = limit
+
+		if audio, ok := LoadCachedAudio(vs.cacheKey); ok {
+			FnfLogger.Println("loaded cached audio")
+			vs.usingBgDecoding = false
+			vs.buffer = audio
+			vs.length = int64(len(audio))
+			vs.decodedBytesSize = int64(len(audio))
+			return vs, nil
+		}
+	}
+
 	if decodeAudioInBackground {
 		goto DECODE_BG
 	} else {
@@ -393,6 +542,8 @@ DECODE_EVERYTHING:
 
 var errUndeterminedAudioLength = errors.New("could not determine audio length before decoding")
 
+var errStreamingUnsupported = errors.New("audio can't be streamed")
+
 func (vs *VaryingSpeedStream) startBgDecoding(rawFile []byte, fileType string) error {
 	decoder, decoderErr := NewAudioDeocoder(rawFile, fileType)
 
@@ -406,59 +557,118 @@ func (vs *VaryingSpeedStream) startBgDecoding(rawFile []byte, fileType string) e
 		return errUndeterminedAudioLength
 	}
 
+	vs.decodeInBackground(decoder, length)
+
+	return nil
+}
+
+// Decodes audio to vs.buffer on a goroutine until it's done or QuitBackgroundDecoding is called.
+func (vs *VaryingSpeedStream) decodeInBackground(decoder AudioDecoder, length int64) {
 	vs.usingBgDecoding = true
 
 	vs.length = length
-	vs.bgDecoderQueue = make(chan byte, length)
-	vs.buffer = make([]byte, 0, length)
+	vs.buffer = make([]byte, length)
 
-	go func() {
-		buffer := make([]byte, 0, BytesPerSample*16)
-		sent := int64(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	vs.bgDecoderCancel = cancel
 
-		for {
-			buff := buffer[:cap(buffer)]
+	go func() {
+		defer cancel()
 
-			n, err := decoder.Read(buff)
+		const readSize = BytesPerSample * 1024
 
-			sent += int64(n)
+		sent := int64(0)
 
-			buff = buff[:n]
+		var decodeErr error
 
-			for _, b := range buff {
-				vs.bgDecoderQueue <- b
+		for sent < length {
+			if ctx.Err() != nil {
+				break
 			}
 
-			doBreak := false
+			// NOTE : we only write to the part of the buffer that readSrc doesn't read
+			// so we don't need to lock it
+			n, err := decoder.Read(vs.buffer[sent:min(sent+readSize, length)])
 
-			if err != nil {
-				doBreak = true
-			}
+			sent += int64(n)
 
 			vs.bgDecoderMu.Lock()
 			vs.decodedBytesSize = sent
-			if vs.bgDecoderQuit {
-				doBreak = true
-			}
 			vs.bgDecoderMu.Unlock()
 
-			if doBreak {
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					decodeErr = err
+				} else if sent < length {
+					// file is probably truncated, rest of the buffer stays silent
+					decodeErr = fmt.Errorf(
+						"audio ended at %v, expected %v: %w",
+						ByteLengthToTimeDuration(sent), ByteLengthToTimeDuration(length),
+						io.ErrUnexpectedEOF,
+					)
+				}
 				break
 			}
 		}
 
-		// fill the rest with zeros
-		// we don't care if we stopped midway cause of an error
-		toSend := length - sent
+		vs.bgDecoderMu.Lock()
+		defer vs.bgDecoderMu.Unlock()
+
+		vs.bgDecoderDone = true
 
-		for i := int64(0); i < toSend; i++ {
-			vs.bgDecoderQueue <- 0
+		if decodeErr != nil {
+			ErrorLogger.Printf("failed to decode audio: %v", decodeErr)
+			vs.bgDecoderErr = decodeErr
+			return
+		}
+
+		if ctx.Err() != nil {
+			return
 		}
 
-		vs.bgDecoderMu.Lock()
 		vs.decodedBytesSize = length
-		vs.bgDecoderMu.Unlock()
+
+		if vs.cacheKey != "" {
+			// buffer isn't written to anymore
+			go SaveCachedAudio(vs.cacheKey, vs.buffer, vs.cacheLimit)
+		}
 	}()
+}
+
+func (vs *VaryingSpeedStream) startStreaming(rawFile []byte, fileType string) error {
+	var decoder AudioDecoder
+	var err error
+
+	// decoders for these formats decode the whole file when they are created
+	// so we use one that decodes flac a frame at a time
+	// and give up on opus
+	if strings.HasSuffix(strings.ToLower(fileType), "flac") {
+		decoder, err = newFlacDecoder(rawFile)
+	} else if strings.HasSuffix(strings.ToLower(fileType), "opus") {
+		err = fmt.Errorf("%w: opus files can't be streamed", errStreamingUnsupported)
+	} else {
+		decoder, err = NewAudioDeocoder(rawFile, fileType)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	length := decoder.Length()
+
+	if length <= 0 {
+		return errUndeterminedAudioLength
+	}
+
+	vs.usingBgDecoding = false
+	vs.streamer = newAudioStreamer(decoder, length)
+	vs.length = length
+	vs.decodedBytesSize = length
+
+	ctx, cancel := context.WithCancel(context.Background())
+	vs.bgDecoderCancel = cancel
+
+	go vs.streamer.run(ctx)
 
 	return nil
 }
@@ -469,6 +679,11 @@ func (vs *VaryingSpeedStream) decodeWholeAudio(rawFile []byte, fileType string)
 		vs.buffer = buffer
 		vs.length = int64(len(buffer))
 		vs.decodedBytesSize = int64(len(buffer))
+
+		if vs.cacheKey != "" {
+			go SaveCachedAudio(vs.cacheKey, buffer, vs.cacheLimit)
+		}
+
 		return nil
 	} else {
 		return err
@@ -498,39 +713,92 @@ func NewVaryingSpeedStreamFromDecodedAudio(decodedAudio []byte, padStart, padEnd
 	return vs
 }
 
-func (vs *VaryingSpeedStream) readSrc(at int64) byte {
+// Returns sample at given byte position, at should be aligned to BytesPerSample.
+func (vs *VaryingSpeedStream) readSrc(at int64) [BytesPerSample]byte {
 	if at < vs.padStart {
-		return 0
+		return [BytesPerSample]byte{}
 	}
 
 	if at >= vs.padStart+vs.length {
-		return 0
+		return [BytesPerSample]byte{}
 	}
 
 	at -= vs.padStart
 
-	if at < int64(len(vs.buffer)) {
-		return vs.buffer[at]
+	if vs.streamer != nil {
+		return vs.streamer.sampleAt(at)
 	}
 
-	if vs.usingBgDecoding {
-		for at >= int64(len(vs.buffer)) {
-			b := <-vs.bgDecoderQueue
-			vs.buffer = append(vs.buffer, b)
-		}
+	// play silence rather than waiting for it to be decoded
+	if at+BytesPerSample > vs.readableSize {
+		return [BytesPerSample]byte{}
 	}
 
-	return vs.buffer[at]
+	return [BytesPerSample]byte(vs.buffer[at:])
 }
 
 func (vs *VaryingSpeedStream) Read(p []byte) (int, error) {
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
+	if vs.usingBgDecoding {
+		vs.bgDecoderMu.Lock()
+		vs.readableSize = vs.decodedBytesSize
+		vs.bgDecoderMu.Unlock()
+	} else {
+		vs.readableSize = vs.length
+	}
+
+	if vs.clicks != nil {
+		return vs.readClicks(p)
+	}
+
+	if vs.streamer != nil {
+		vs.streamer.mu.Lock()
+		defer func() {
+			vs.streamer.setReadPosition(vs.bytePosition - vs.padStart)
+			vs.streamer.mu.Unlock()
+		}()
+	}
+
 	wCursor := 0
 	wCursorLimit := (len(p) / BytesPerSample) * BytesPerSample
 
-	floatPosition := float64(vs.bytePosition)
+	// NOTE : at normal speed just copying samples is exact
+	if vs.preservePitch && vs.speed != 1 {
+		if !vs.stretcher.active {
+			// stretcher works on whole samples
+			vs.fracPosition = 0
+			vs.stretcher.reset(vs, vs.bytePosition/BytesPerSample)
+		}
+
+		for {
+			if vs.bytePosition+BytesPerSample >= vs.audioBytesSize() {
+				return wCursor, io.EOF
+			}
+
+			if wCursor+BytesPerSample >= wCursorLimit {
+				return wCursor, nil
+			}
+
+			l, r := vs.stretcher.next(vs)
+
+			p[wCursor+0] = byte(uint16(l))
+			p[wCursor+1] = byte(uint16(l) >> 8)
+			p[wCursor+2] = byte(uint16(r))
+			p[wCursor+3] = byte(uint16(r) >> 8)
+
+			wCursor += BytesPerSample
+
+			vs.bytePosition = vs.stretcher.bytePosition()
+		}
+	}
+
+	// resampler starts from where stretcher left off
+	if vs.stretcher.active {
+		vs.stretcher.active = false
+		vs.fracPosition = 0
+	}
 
 	for {
 		if vs.bytePosition+BytesPerSample >= vs.audioBytesSize() {
@@ -541,19 +809,34 @@ func (vs *VaryingSpeedStream) Read(p []byte) (int, error) {
 			return wCursor, nil
 		}
 
-		p[wCursor+0] = vs.readSrc(vs.bytePosition + 0)
-		p[wCursor+1] = vs.readSrc(vs.bytePosition + 1)
-		p[wCursor+2] = vs.readSrc(vs.bytePosition + 2)
-		p[wCursor+3] = vs.readSrc(vs.bytePosition + 3)
+		if vs.speed == 1 && vs.fracPosition == 0 {
+			sample := vs.readSrc(vs.bytePosition)
+			copy(p[wCursor:], sample[:])
+		} else {
+			l, r := interpolateStreamSample(vs, vs.bytePosition/BytesPerSample, vs.fracPosition)
+
+			p[wCursor+0] = byte(uint16(l))
+			p[wCursor+1] = byte(uint16(l) >> 8)
+			p[wCursor+2] = byte(uint16(r))
+			p[wCursor+3] = byte(uint16(r) >> 8)
+		}
 
 		wCursor += BytesPerSample
 
-		floatPosition += vs.speed * BytesPerSample
-
-		vs.bytePosition = (int64(floatPosition) / BytesPerSample) * BytesPerSample
+		vs.advance()
 	}
 }
 
+// Moves position by one sample at current speed.
+func (vs *VaryingSpeedStream) advance() {
+	// keep fractional position so that it doesn't drift between reads
+	samplePos := f64(vs.bytePosition/BytesPerSample) + vs.fracPosition + vs.speed
+	whole := math.Floor(samplePos)
+
+	vs.bytePosition = int64(whole) * BytesPerSample
+	vs.fracPosition = samplePos - whole
+}
+
 func (vs *VaryingSpeedStream) Seek(offset int64, whence int) (int64, error) {
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
@@ -580,6 +863,22 @@ func (vs *VaryingSpeedStream) Seek(offset int64, whence int) (int64, error) {
 
 	vs.bytePosition = abs
 
+	vs.fracPosition = 0
+
+	// stretcher has to start over from new position
+	vs.stretcher.active = false
+
+	if vs.clicks != nil {
+		vs.clicks.seek(vs.bytePosition)
+	}
+
+	// start decoding where we are going to read
+	if vs.streamer != nil {
+		vs.streamer.mu.Lock()
+		vs.streamer.setReadPosition(vs.bytePosition - vs.padStart)
+		vs.streamer.mu.Unlock()
+	}
+
 	return vs.bytePosition, nil
 }
 
@@ -597,6 +896,13 @@ func (vs *VaryingSpeedStream) SetSpeed(speed float64) {
 	vs.speed = speed
 }
 
+func (vs *VaryingSpeedStream) SetPreservePitch(preservePitch bool) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	vs.preservePitch = preservePitch
+}
+
 func (vs *VaryingSpeedStream) BytePosition() int64 {
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
@@ -633,9 +939,27 @@ func (vs *VaryingSpeedStream) DecodedDuration() time.Duration {
 }
 
 func (vs *VaryingSpeedStream) QuitBackgroundDecoding() {
+	if vs.bgDecoderCancel != nil {
+		vs.bgDecoderCancel()
+	}
+}
+
+// Returns true if audio is fully decoded or if decoding has stopped.
+func (vs *VaryingSpeedStream) IsDecodingDone() bool {
+	if !vs.usingBgDecoding {
+		return true
+	}
+
+	vs.bgDecoderMu.Lock()
+	defer vs.bgDecoderMu.Unlock()
+	return vs.bgDecoderDone
+}
+
+// Returns error that stopped background decoding.
+func (vs *VaryingSpeedStream) DecodingError() error {
 	vs.bgDecoderMu.Lock()
 	defer vs.bgDecoderMu.Unlock()
-	vs.bgDecoderQuit = true
+	return vs.bgDecoderErr
 }
 
 // This is directly copied from ebiten's Time stream struct
@@ -678,6 +1002,11 @@ func DecodeWholeAudio(rawFile []byte, fileType string) ([]byte, error) {
 		if decoder, err := NewAudioDeocoder(rawFile, fileType); err != nil {
 			return nil, err
 		} else {
+			// these are already decoded when they are created
+			// so there's no point in making more of them
+			if pd, ok := decoder.(pcmDecoder); ok {
+				return io.ReadAll(pd)
+			}
 			decoders = append(decoders, decoder)
 		}
 	}
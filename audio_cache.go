@@ -0,0 +1,214 @@
+package fnf
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decoded audio is saved to disk so that songs we opened before load fast.
+//
+// Each file in the cache directory is raw decoded audio named after
+// hash of the audio file and the sample rate.
+// Last time a file was used is stored as its modification time,
+// and least recently used ones are removed when cache gets too big.
+
+const audioCacheExt = ".pcm"
+
+// guards files in the cache directory since we write to it from other goroutines
+var audioCacheMu sync.Mutex
+
+func AudioCacheDir() (string, error) {
+	return RelativePath(AudioCacheDirPath)
+}
+
+func AudioCacheKey(rawFile []byte) string {
+	return fmt.Sprintf("%x-%d", sha256.Sum256(rawFile), SampleRate)
+}
+
+// Size limit of the cache in bytes.
+func AudioCacheLimit() int64 {
+	return int64(TheOptions.AudioCacheSize) * 1024 * 1024
+}
+
+func audioCachePath(key string) (string, error) {
+	dir, err := AudioCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key+audioCacheExt), nil
+}
+
+// Returns decoded audio if it's in the cache.
+func LoadCachedAudio(key string) ([]byte, bool) {
+	audioCacheMu.Lock()
+	defer audioCacheMu.Unlock()
+
+	path, err := audioCachePath(key)
+	if err != nil {
+		ErrorLogger.Printf("failed to load cached audio: %v", err)
+		return nil, false
+	}
+
+	audio, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			ErrorLogger.Printf("failed to load cached audio: %v", err)
+		}
+		return nil, false
+	}
+
+	if len(audio)%BytesPerSample != 0 {
+		ErrorLogger.Printf("cached audio %v is broken, removing it", path)
+		os.Remove(path)
+		return nil, false
+	}
+
+	// mark it as recently used
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		ErrorLogger.Printf("failed to update cached audio time: %v", err)
+	}
+
+	return audio, true
+}
+
+// Saves decoded audio to the cache and removes old ones
+// until cache is smaller than limit.
+//
+// limit is passed in rather than read from TheOptions
+// since it's usually called from another goroutine.
+func SaveCachedAudio(key string, audio []byte, limit int64) {
+	if limit <= 0 || int64(len(audio)) > limit {
+		return
+	}
+
+	audioCacheMu.Lock()
+	defer audioCacheMu.Unlock()
+
+	dir, err := AudioCacheDir()
+	if err != nil {
+		ErrorLogger.Printf("failed to save cached audio: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ErrorLogger.Printf("failed to save cached audio: %v", err)
+		return
+	}
+
+	path := filepath.Join(dir, key+audioCacheExt)
+
+	// write to temporary file first
+	// so that we never end up with half written audio
+	tmpPath := path + ".tmp"
+
+	if err := os.WriteFile(tmpPath, audio, 0664); err != nil {
+		ErrorLogger.Printf("failed to save cached audio: %v", err)
+		os.Remove(tmpPath)
+		return
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		ErrorLogger.Printf("failed to save cached audio: %v", err)
+		os.Remove(tmpPath)
+		return
+	}
+
+	if err := evictAudioCacheImpl(limit); err != nil {
+		ErrorLogger.Printf("failed to evict audio cache: %v", err)
+	}
+}
+
+// Removes least recently used audio until cache is smaller than limit.
+func EvictAudioCache(limit int64) error {
+	audioCacheMu.Lock()
+	defer audioCacheMu.Unlock()
+
+	return evictAudioCacheImpl(limit)
+}
+
+func evictAudioCacheImpl(limit int64) error {
+	dir, err := AudioCacheDir()
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	type cachedFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []cachedFile
+	var totalSize int64
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		// left over from when we crashed while saving
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			os.Remove(path)
+			continue
+		}
+
+		if !strings.HasSuffix(entry.Name(), audioCacheExt) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, cachedFile{
+			path:    path,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+
+		totalSize += info.Size()
+	}
+
+	// oldest first
+	slices.SortFunc(files, func(a, b cachedFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	for _, file := range files {
+		if totalSize <= limit {
+			break
+		}
+
+		if err := os.Remove(file.path); err != nil {
+			return err
+		}
+
+		totalSize -= file.size
+	}
+
+	return nil
+}
+
+func ClearAudioCache() error {
+	return EvictAudioCache(0)
+}
@@ -0,0 +1,386 @@
+package fnf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// Decoder for formats that we decode all at once.
+//
+// It holds 44100Hz stereo 16 bit audio, same as what other decoders give us.
+type pcmDecoder struct {
+	*bytes.Reader
+}
+
+func (pd pcmDecoder) Length() int64 {
+	return pd.Size()
+}
+
+// Takes interleaved samples with given number of channels and sample rate,
+// and returns decoder that plays them as stereo audio at SampleRate.
+//
+// Only the first two channels are used.
+func newPcmDecoder(samples []int16, channels int, sampleRate int) (pcmDecoder, error) {
+	if channels <= 0 {
+		return pcmDecoder{}, fmt.Errorf("invalid channel count %v", channels)
+	}
+	if sampleRate <= 0 {
+		return pcmDecoder{}, fmt.Errorf("invalid sample rate %v", sampleRate)
+	}
+
+	frameCount := len(samples) / channels
+
+	stereo := make([]int16, frameCount*2)
+
+	for i := range frameCount {
+		l := samples[i*channels]
+		r := l
+		if channels >= 2 {
+			r = samples[i*channels+1]
+		}
+		stereo[i*2+0] = l
+		stereo[i*2+1] = r
+	}
+
+	if sampleRate != SampleRate {
+		stereo = resampleStereo(stereo, sampleRate, SampleRate)
+	}
+
+	audio := make([]byte, len(stereo)*2)
+
+	for i, s := range stereo {
+		binary.LittleEndian.PutUint16(audio[i*2:], uint16(s))
+	}
+
+	return pcmDecoder{bytes.NewReader(audio)}, nil
+}
+
+func decodeFlac(r io.Reader) (pcmDecoder, error) {
+	stream, err := flac.New(r)
+	if err != nil {
+		return pcmDecoder{}, err
+	}
+	defer stream.Close()
+
+	channels := int(stream.Info.NChannels)
+	bitsPerSample := int(stream.Info.BitsPerSample)
+
+	if bitsPerSample <= 0 || bitsPerSample > 32 {
+		return pcmDecoder{}, fmt.Errorf("invalid bits per sample %v", bitsPerSample)
+	}
+
+	samples := make([]int16, 0, stream.Info.NSamples*uint64(channels))
+
+	for {
+		frame, err := stream.ParseNext()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return pcmDecoder{}, err
+		}
+
+		if len(frame.Subframes) != channels {
+			return pcmDecoder{}, fmt.Errorf(
+				"frame has %v channels, expected %v", len(frame.Subframes), channels)
+		}
+
+		for i := range int(frame.BlockSize) {
+			for _, subframe := range frame.Subframes {
+				samples = append(samples, flacSampleTo16Bit(subframe.Samples[i], bitsPerSample))
+			}
+		}
+	}
+
+	return newPcmDecoder(samples, channels, int(stream.Info.SampleRate))
+}
+
+func flacSampleTo16Bit(s int32, bitsPerSample int) int16 {
+	if bitsPerSample > 16 {
+		s >>= bitsPerSample - 16
+	} else {
+		s <<= 16 - bitsPerSample
+	}
+	return int16(s)
+}
+
+// Decoder that decodes flac a frame at a time, used when streaming
+// so we don't have to decode the whole file up front like decodeFlac.
+//
+// It can't resample, so it only takes flac that is already at SampleRate.
+type flacDecoder struct {
+	reader *bytes.Reader
+
+	bitsPerSample int
+	channels      int
+
+	length   int64
+	position int64
+
+	// current frame as 16 bit stereo
+	frame      []byte
+	frameStart int64 // -1 if we don't have a frame
+
+	// where each frame we went through is in the file and in decoded audio
+	// so that seeking back doesn't decode from the start
+	frameOffsets []int64
+	frameStarts  []int64
+
+	// where frames we haven't gone through start
+	indexedOffset int64
+	indexedEnd    int64
+}
+
+func newFlacDecoder(rawFile []byte) (*flacDecoder, error) {
+	reader := bytes.NewReader(rawFile)
+
+	info, err := readFlacMetadata(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(info.SampleRate) != SampleRate {
+		return nil, fmt.Errorf(
+			"%w: sample rate is %vHz, not %vHz", errStreamingUnsupported, info.SampleRate, SampleRate)
+	}
+
+	if info.NSamples <= 0 {
+		return nil, errUndeterminedAudioLength
+	}
+
+	fd := new(flacDecoder)
+	fd.reader = reader
+
+	fd.channels = int(info.NChannels)
+	fd.bitsPerSample = int(info.BitsPerSample)
+
+	if fd.channels <= 0 {
+		return nil, fmt.Errorf("invalid channel count %v", fd.channels)
+	}
+	if fd.bitsPerSample <= 0 || fd.bitsPerSample > 32 {
+		return nil, fmt.Errorf("invalid bits per sample %v", fd.bitsPerSample)
+	}
+
+	fd.length = int64(info.NSamples) * BytesPerSample
+	fd.frameStart = -1
+
+	fd.indexedOffset, err = reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	return fd, nil
+}
+
+// Reads metadata blocks and leaves reader at the first frame.
+//
+// flac.New does the same but it buffers the reader
+// so we wouldn't know where frames start.
+func readFlacMetadata(reader *bytes.Reader) (*meta.StreamInfo, error) {
+	var signature [4]byte
+
+	if _, err := io.ReadFull(reader, signature[:]); err != nil {
+		return nil, err
+	}
+
+	// skip ID3v2 tag
+	if string(signature[:3]) == "ID3" {
+		var header [6]byte // rest of the version, flags and size
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return nil, err
+		}
+
+		// size is a synchsafe integer
+		size := int64(header[2])<<21 | int64(header[3])<<14 | int64(header[4])<<7 | int64(header[5])
+
+		if _, err := reader.Seek(size, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+
+		if _, err := io.ReadFull(reader, signature[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	if string(signature[:]) != "fLaC" {
+		return nil, fmt.Errorf("invalid flac signature %q", signature)
+	}
+
+	block, err := meta.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := block.Body.(*meta.StreamInfo)
+	if !ok {
+		return nil, fmt.Errorf("first metadata block is %T, not stream info", block.Body)
+	}
+
+	for !block.IsLast {
+		block, err = meta.New(reader)
+		if err != nil && !errors.Is(err, meta.ErrReservedType) {
+			return nil, err
+		}
+		if err = block.Skip(); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+func (fd *flacDecoder) Length() int64 {
+	return fd.length
+}
+
+func (fd *flacDecoder) Read(p []byte) (int, error) {
+	if fd.position >= fd.length {
+		return 0, io.EOF
+	}
+
+	n := 0
+
+	for n < len(p) && fd.position < fd.length {
+		if !(fd.frameStart >= 0 &&
+			fd.frameStart <= fd.position && fd.position < fd.frameStart+int64(len(fd.frame))) {
+			if err := fd.loadFrameAt(fd.position); err != nil {
+				return n, err
+			}
+		}
+
+		toCopy := min(int64(len(p)-n), fd.length-fd.position)
+		copied := copy(p[n:n+int(toCopy)], fd.frame[fd.position-fd.frameStart:])
+
+		n += copied
+		fd.position += int64(copied)
+	}
+
+	return n, nil
+}
+
+func (fd *flacDecoder) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = fd.position + offset
+	case io.SeekEnd:
+		abs = fd.length + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %v", whence)
+	}
+
+	if abs < 0 {
+		return 0, fmt.Errorf("negative position %v", abs)
+	}
+
+	fd.position = abs
+
+	return abs, nil
+}
+
+// Decodes the frame that has audio at given position.
+func (fd *flacDecoder) loadFrameAt(at int64) error {
+	// we are just playing along, reader is already at the next frame
+	if fd.frameStart >= 0 && at == fd.frameStart+int64(len(fd.frame)) {
+		return fd.parseFrame(at)
+	}
+
+	// we went through that frame before
+	if at < fd.indexedEnd {
+		i, found := slices.BinarySearch(fd.frameStarts, at)
+		if !found {
+			i--
+		}
+
+		if _, err := fd.reader.Seek(fd.frameOffsets[i], io.SeekStart); err != nil {
+			return err
+		}
+
+		return fd.parseFrame(fd.frameStarts[i])
+	}
+
+	// go through frames we haven't seen until we get there
+	if _, err := fd.reader.Seek(fd.indexedOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	start := fd.indexedEnd
+
+	for {
+		if err := fd.parseFrame(start); err != nil {
+			return err
+		}
+
+		start += int64(len(fd.frame))
+
+		if at < start {
+			return nil
+		}
+	}
+}
+
+// Decodes frame at the reader's position that starts at given position in decoded audio.
+func (fd *flacDecoder) parseFrame(start int64) error {
+	offset, err := fd.reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	f, err := frame.Parse(fd.reader)
+	if err != nil {
+		// we know how long the audio should be
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	if len(f.Subframes) != fd.channels {
+		return fmt.Errorf(
+			"frame has %v channels, expected %v", len(f.Subframes), fd.channels)
+	}
+
+	if f.BlockSize <= 0 {
+		return fmt.Errorf("frame has no samples")
+	}
+
+	blockSize := int(f.BlockSize)
+
+	fd.frame = slices.Grow(fd.frame[:0], blockSize*BytesPerSample)[:blockSize*BytesPerSample]
+	fd.frameStart = start
+
+	for i := range blockSize {
+		l := flacSampleTo16Bit(f.Subframes[0].Samples[i], fd.bitsPerSample)
+		r := l
+		if fd.channels >= 2 {
+			r = flacSampleTo16Bit(f.Subframes[1].Samples[i], fd.bitsPerSample)
+		}
+
+		binary.LittleEndian.PutUint16(fd.frame[i*BytesPerSample:], uint16(l))
+		binary.LittleEndian.PutUint16(fd.frame[i*BytesPerSample+2:], uint16(r))
+	}
+
+	if offset == fd.indexedOffset {
+		fd.frameOffsets = append(fd.frameOffsets, offset)
+		fd.frameStarts = append(fd.frameStarts, start)
+
+		fd.indexedEnd = start + int64(len(fd.frame))
+		fd.indexedOffset, err = fd.reader.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
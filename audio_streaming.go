@@ -0,0 +1,196 @@
+package fnf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Streaming decodes audio a chunk at a time around where it's being read
+// so memory we use doesn't depend on how long the audio is.
+//
+// Chunks are decoded on a goroutine ahead of the read position,
+// so reading (which happens in oto's callback) never waits for the decoder.
+// Chunk that isn't decoded yet (right after seeking for example) is played as silence.
+//
+// Chunks are kept in a ring of streamChunkCount slots
+// and chunk i always goes to slot i % streamChunkCount,
+// so seeking back a little (rewinding, looping) doesn't decode again.
+
+const (
+	streamChunkSize = SampleRate / 4 * BytesPerSample // 250ms
+
+	// oto reads 200ms at a time (see VaryingSpeedPlayer.loadAudioImpl)
+	// so this is enough to stay ahead of it up to about 4x speed
+	streamPrefetchChunks = 4
+
+	// chunks behind the read position are kept for resampler and time stretcher
+	// which look back a little, and for short rewinds
+	streamChunkCount = streamPrefetchChunks + 4
+)
+
+type audioStreamer struct {
+	decoder AudioDecoder
+	length  int64
+
+	// where decoder will read from next, -1 if we don't know
+	// only used by decoding goroutine
+	decoderPos int64
+
+	// chunk decoding goroutine decodes to, swapped with a slot in the ring
+	spare []byte
+
+	// wakes up decoding goroutine
+	wake chan struct{}
+
+	// locked while reading so that ring doesn't change under the reader
+	mu sync.Mutex
+
+	ring [streamChunkCount][]byte
+
+	// which chunk is in each slot, -1 if slot is empty
+	ringChunks [streamChunkCount]int64
+
+	// chunk at the read position
+	readChunk int64
+}
+
+func newAudioStreamer(decoder AudioDecoder, length int64) *audioStreamer {
+	as := new(audioStreamer)
+	as.decoder = decoder
+	as.length = length
+
+	as.spare = make([]byte, streamChunkSize)
+	as.wake = make(chan struct{}, 1)
+
+	for i := range streamChunkCount {
+		as.ring[i] = make([]byte, streamChunkSize)
+		as.ringChunks[i] = -1
+	}
+
+	return as
+}
+
+// Decodes chunks ahead of the read position until ctx is canceled.
+func (as *audioStreamer) run(ctx context.Context) {
+	for {
+		chunk, ok := as.nextChunkToDecode()
+
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-as.wake:
+			}
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		as.decodeChunk(chunk)
+
+		as.mu.Lock()
+
+		// don't throw away a chunk that's still needed
+		// if reader moved elsewhere while we were decoding
+		if as.isChunkWanted(chunk) {
+			slot := chunk % streamChunkCount
+
+			as.ring[slot], as.spare = as.spare, as.ring[slot]
+			as.ringChunks[slot] = chunk
+		}
+
+		as.mu.Unlock()
+	}
+}
+
+func (as *audioStreamer) chunkTotal() int64 {
+	return (as.length + streamChunkSize - 1) / streamChunkSize
+}
+
+// Should be called with mu locked.
+func (as *audioStreamer) isChunkWanted(chunk int64) bool {
+	return as.readChunk <= chunk && chunk <= as.readChunk+streamPrefetchChunks &&
+		chunk < as.chunkTotal()
+}
+
+// Returns the closest chunk to read position that isn't decoded yet.
+func (as *audioStreamer) nextChunkToDecode() (int64, bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	for chunk := as.readChunk; as.isChunkWanted(chunk); chunk++ {
+		if as.ringChunks[chunk%streamChunkCount] != chunk {
+			return chunk, true
+		}
+	}
+
+	return 0, false
+}
+
+// Decodes chunk to as.spare.
+func (as *audioStreamer) decodeChunk(chunk int64) {
+	start := chunk * streamChunkSize
+	buffer := as.spare
+
+	// we don't need to seek if we are just playing along
+	if as.decoderPos != start {
+		if _, err := as.decoder.Seek(start, io.SeekStart); err != nil {
+			ErrorLogger.Printf("failed to seek while streaming audio: %v", err)
+			clear(buffer)
+			as.decoderPos = -1
+			return
+		}
+		as.decoderPos = start
+	}
+
+	n, err := io.ReadFull(as.decoder, buffer)
+
+	as.decoderPos += int64(n)
+
+	// fill the rest with zeros
+	// we don't care if we stopped midway cause of an error
+	clear(buffer[n:])
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		ErrorLogger.Printf("failed to decode while streaming audio: %v", err)
+		as.decoderPos = -1
+	}
+}
+
+// Tells decoding goroutine where we are reading (padding not included).
+//
+// Should be called with mu locked.
+func (as *audioStreamer) setReadPosition(at int64) {
+	chunk := max(at, 0) / streamChunkSize
+
+	if chunk != as.readChunk {
+		as.readChunk = chunk
+		as.wakeUp()
+	}
+}
+
+func (as *audioStreamer) wakeUp() {
+	select {
+	case as.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Returns decoded sample at given byte position (padding not included),
+// silence if it isn't decoded yet.
+//
+// at should be aligned to BytesPerSample and mu should be locked.
+func (as *audioStreamer) sampleAt(at int64) [BytesPerSample]byte {
+	chunk := at / streamChunkSize
+	slot := chunk % streamChunkCount
+
+	if as.ringChunks[slot] != chunk {
+		return [BytesPerSample]byte{}
+	}
+
+	return [BytesPerSample]byte(as.ring[slot][at%streamChunkSize:])
+}
@@ -0,0 +1,339 @@
+package fnf
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// Makes decoded stereo sine wave that is given seconds long.
+func testSineAudio(seconds int) []byte {
+	audio := make([]byte, seconds*SampleRate*BytesPerSample)
+
+	for i := range seconds * SampleRate {
+		v := int16(math.Sin(2*math.Pi*440*f64(i)/SampleRate) * 0.5 * math.MaxInt16)
+
+		binary.LittleEndian.PutUint16(audio[i*BytesPerSample:], uint16(v))
+		binary.LittleEndian.PutUint16(audio[i*BytesPerSample+2:], uint16(v))
+	}
+
+	return audio
+}
+
+func BenchmarkVaryingSpeedStreamRead(b *testing.B) {
+	audio := testSineAudio(10)
+
+	for _, preservePitch := range []bool{false, true} {
+		for _, speed := range []float64{0.75, 1.25, 1.7} {
+			b.Run(fmt.Sprintf("speed=%v/preservePitch=%v", speed, preservePitch), func(b *testing.B) {
+				vs := NewVaryingSpeedStreamFromDecodedAudio(audio, 0, 0)
+				vs.SetSpeed(speed)
+				vs.SetPreservePitch(preservePitch)
+
+				// about what oto asks for at a time
+				p := make([]byte, 4096)
+
+				b.SetBytes(int64(len(p)))
+				b.ResetTimer()
+
+				for range b.N {
+					if _, err := vs.Read(p); err == io.EOF {
+						vs.Seek(0, io.SeekStart)
+					}
+				}
+			})
+		}
+	}
+}
+
+// AudioDecoder over already decoded audio.
+type testPcmDecoder struct {
+	*bytes.Reader
+}
+
+func (d testPcmDecoder) Length() int64 {
+	return d.Size()
+}
+
+func TestAudioStreamerRead(t *testing.T) {
+	audio := testSineAudio(3)
+
+	vs := new(VaryingSpeedStream)
+	vs.speed = 1
+	vs.length = int64(len(audio))
+	vs.decodedBytesSize = vs.length
+	vs.streamer = newAudioStreamer(testPcmDecoder{bytes.NewReader(audio)}, vs.length)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go vs.streamer.run(ctx)
+
+	isLoaded := func(at int64) bool {
+		vs.streamer.mu.Lock()
+		defer vs.streamer.mu.Unlock()
+
+		chunk := at / streamChunkSize
+		return vs.streamer.ringChunks[chunk%streamChunkCount] == chunk
+	}
+
+	for _, seekTo := range []int64{0, 2 * SampleRate, SampleRate / 2} {
+		at := seekTo * BytesPerSample
+
+		vs.Seek(at, io.SeekStart)
+
+		deadline := time.Now().Add(time.Second * 5)
+		for !isLoaded(at) || !isLoaded(at+4096) {
+			if time.Now().After(deadline) {
+				t.Fatalf("chunk at %v was never decoded", seekTo)
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		p := make([]byte, 4096)
+		n, _ := vs.Read(p)
+
+		if !bytes.Equal(p[:n], audio[at:at+int64(n)]) {
+			t.Errorf("audio read at %v doesn't match decoded audio", seekTo)
+		}
+	}
+}
+
+// Encodes decoded stereo audio as flac with given sample rate.
+//
+// Frames are blockSize samples long except the last one.
+func testFlac(t *testing.T, audio []byte, sampleRate int, blockSize int) []byte {
+	sampleCount := len(audio) / BytesPerSample
+
+	info := &meta.StreamInfo{
+		BlockSizeMin:  16,
+		BlockSizeMax:  uint16(blockSize),
+		SampleRate:    uint32(sampleRate),
+		NChannels:     2,
+		BitsPerSample: 16,
+		NSamples:      uint64(sampleCount),
+	}
+
+	var out bytes.Buffer
+
+	enc, err := flac.NewEncoder(&out, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for start := 0; start < sampleCount; start += blockSize {
+		size := min(blockSize, sampleCount-start)
+
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(size),
+				SampleRate:        uint32(sampleRate),
+				Channels:          frame.ChannelsLR,
+				BitsPerSample:     16,
+			},
+		}
+
+		for channel := range 2 {
+			samples := make([]int32, size)
+			for i := range samples {
+				at := (start+i)*BytesPerSample + channel*2
+				samples[i] = int32(int16(binary.LittleEndian.Uint16(audio[at:])))
+			}
+
+			f.Subframes = append(f.Subframes, &frame.Subframe{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   samples,
+				NSamples:  size,
+			})
+		}
+
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.Bytes()
+}
+
+func TestFlacDecoderSeek(t *testing.T) {
+	// last frame is shorter than the others
+	audio := testSineAudio(1)[:(SampleRate-100)*BytesPerSample]
+
+	fd, err := newFlacDecoder(testFlac(t, audio, SampleRate, 4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fd.Length() != int64(len(audio)) {
+		t.Fatalf("length is %v, expected %v", fd.Length(), len(audio))
+	}
+
+	// forward past frames we haven't decoded, back to ones we have, into the last frame
+	// and reading across the frame boundary
+	for _, sample := range []int64{0, 30000, 100, 4000, SampleRate - 200, 5000} {
+		at := sample * BytesPerSample
+
+		if _, err := fd.Seek(at, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+
+		p := make([]byte, 1000*BytesPerSample)
+		n, err := io.ReadFull(fd, p)
+
+		end := min(at+int64(len(p)), int64(len(audio)))
+
+		if n != int(end-at) {
+			t.Fatalf("read %v bytes at sample %v, expected %v (%v)", n, sample, end-at, err)
+		}
+
+		if !bytes.Equal(p[:n], audio[at:end]) {
+			t.Errorf("audio read at sample %v doesn't match", sample)
+		}
+	}
+
+	// audio at other sample rates has to be resampled which we can't do a frame at a time
+	if _, err := newFlacDecoder(testFlac(t, audio, 48000, 4096)); !errors.Is(err, errStreamingUnsupported) {
+		t.Errorf("got %v for 48000Hz flac, expected errStreamingUnsupported", err)
+	}
+}
+
+// Cuts out middle pages of an ogg file but keeps the last page,
+// so that its length can be read but it ends early when decoded.
+func testTruncatedOgg(t *testing.T) []byte {
+	raw, err := os.ReadFile("assets/hit-sound.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pages []int
+	for at := 0; ; {
+		i := bytes.Index(raw[at:], []byte("OggS"))
+		if i < 0 {
+			break
+		}
+		pages = append(pages, at+i)
+		at += i + 1
+	}
+
+	if len(pages) < 4 {
+		t.Fatalf("expected ogg to have at least 4 pages, got %v", len(pages))
+	}
+
+	last := pages[len(pages)-1]
+	cut := pages[len(pages)-3]
+
+	return append(slices.Clone(raw[:cut]), raw[last:]...)
+}
+
+func waitForDecoding(t *testing.T, vs *VaryingSpeedStream) {
+	deadline := time.Now().Add(time.Second * 5)
+
+	for !vs.IsDecodingDone() {
+		if time.Now().After(deadline) {
+			t.Fatal("background decoding didn't finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBgDecodingTruncatedOgg(t *testing.T) {
+	vs := new(VaryingSpeedStream)
+	vs.speed = 1
+
+	if err := vs.startBgDecoding(testTruncatedOgg(t), ".ogg"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForDecoding(t, vs)
+
+	if err := vs.DecodingError(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("DecodingError() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	decoded := vs.DecodedBytesSize()
+	if decoded >= vs.AudioBytesSize() {
+		t.Fatalf("decoded %v bytes out of %v, expected it to end early", decoded, vs.AudioBytesSize())
+	}
+
+	vs.Seek(decoded-decoded%BytesPerSample, io.SeekStart)
+
+	p := make([]byte, 4096)
+	n, _ := vs.Read(p)
+
+	if n <= 0 {
+		t.Fatal("nothing was read past decoded audio")
+	}
+
+	for _, b := range p[:n] {
+		if b != 0 {
+			t.Fatal("audio past decoded part isn't silent")
+		}
+	}
+}
+
+// Decoder that blocks on each read until release is closed.
+type testBlockingDecoder struct {
+	release chan struct{}
+	length  int64
+}
+
+func (d *testBlockingDecoder) Read(p []byte) (int, error) {
+	<-d.release
+	clear(p)
+	return len(p), nil
+}
+
+func (d *testBlockingDecoder) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("can't seek")
+}
+
+func (d *testBlockingDecoder) Length() int64 {
+	return d.length
+}
+
+func TestBgDecodingCancel(t *testing.T) {
+	decoder := &testBlockingDecoder{
+		release: make(chan struct{}),
+		length:  SampleRate * BytesPerSample * 60,
+	}
+
+	vs := new(VaryingSpeedStream)
+	vs.speed = 1
+
+	vs.decodeInBackground(decoder, decoder.length)
+
+	if vs.IsDecodingDone() {
+		t.Fatal("decoding finished before decoder returned anything")
+	}
+
+	vs.QuitBackgroundDecoding()
+	close(decoder.release)
+
+	waitForDecoding(t, vs)
+
+	if err := vs.DecodingError(); err != nil {
+		t.Errorf("DecodingError() = %v, want nil", err)
+	}
+
+	// it should have stopped right after the read that was blocked
+	if decoded := vs.DecodedBytesSize(); decoded >= decoder.length {
+		t.Errorf("decoded the whole audio (%v bytes) after being canceled", decoded)
+	}
+}
@@ -0,0 +1,43 @@
+package fnf
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+type SongBookMark struct {
+	// NOTE : this is song time, it doesn't include GSC.PadStart
+	Time time.Duration
+	Name string
+}
+
+// Key used to store per song data (bookmarks, play history etc...).
+//
+// Each difficulty has it's own chart file so chart path is unique to
+// path group and difficulty.
+func SongDataKey(group FnfPathGroup, difficulty FnfDifficulty) string {
+	return filepath.Clean(group.SongPaths[difficulty])
+}
+
+var TheBookMarks = make(map[string][]SongBookMark)
+
+func GetBookMarks(key string) []SongBookMark {
+	return slices.Clone(TheBookMarks[key])
+}
+
+func SetBookMarks(key string, bookMarks []SongBookMark) {
+	if len(bookMarks) <= 0 {
+		delete(TheBookMarks, key)
+		return
+	}
+
+	bookMarks = slices.Clone(bookMarks)
+
+	slices.SortFunc(bookMarks, func(a, b SongBookMark) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+
+	TheBookMarks[key] = bookMarks
+}
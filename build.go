@@ -23,6 +23,7 @@ var (
 	FlagDebug = flag.Bool("debug", false, "disable inlining and optimization")
 	FlagPprof = flag.Bool("pprof", false, "enable pprof")
 	FlagDemo  = flag.Bool("demo", false, "enable demo recording for main app")
+	FlagOpus  = flag.Bool("opus", false, "enable opus decoding (needs cgo and libopusfile)")
 )
 
 func init() {
@@ -113,7 +114,7 @@ func main() {
 		dst = AddExeIfWindows(dst)
 
 		return BuildApp(
-			src, dst, *FlagDebug, *FlagPprof, *FlagDemo,
+			src, dst, *FlagDebug, *FlagPprof, *FlagDemo, *FlagOpus,
 		)
 	}
 
@@ -127,7 +128,7 @@ func main() {
 		}
 
 		return BuildApp(
-			src, dst, *FlagDebug, *FlagPprof, false,
+			src, dst, *FlagDebug, *FlagPprof, false, false,
 		)
 	}
 
@@ -164,7 +165,7 @@ func main() {
 		if err := BuildApp(
 			"main.go",
 			filepath.Join(releaseFolder, AddExeIfWindows("fnf-practice")),
-			false, false, false,
+			false, false, false, *FlagOpus,
 		); err.IsFail {
 			return err
 		}
@@ -302,7 +303,7 @@ func MkDir(path string) SimpleError {
 
 func BuildApp(
 	src, dst string,
-	debug, pprof, demo bool,
+	debug, pprof, demo, opus bool,
 ) SimpleError {
 	tags := "noaudio"
 
@@ -318,6 +319,10 @@ func BuildApp(
 		tags += ",fnfdebug"
 	}
 
+	if opus {
+		tags += ",fnfopus"
+	}
+
 	gcFlags := "-e"
 
 	if debug {
@@ -0,0 +1,454 @@
+package fnf
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type CalibrationMode int
+
+const (
+	// user taps along to clicks they hear
+	CalibrationAudio CalibrationMode = iota
+	// user taps along to flashes they see
+	CalibrationVisual
+
+	CalibrationModeSize
+)
+
+var CalibrationModeStrs = [CalibrationModeSize]string{
+	"Audio",
+	"Visual",
+}
+
+const (
+	CalibrationBpm = 100
+
+	// how many beats click track has before it loops
+	CalibrationBeats = 32
+
+	// we don't suggest anything until user tapped this many times
+	CalibrationMinTaps = 8
+
+	// how many recent taps we use
+	CalibrationMaxTaps = 64
+
+	calibrationFlashDuration = time.Millisecond * 100
+)
+
+func CalibrationInterval() time.Duration {
+	return time.Minute / CalibrationBpm
+}
+
+type CalibrationScreen struct {
+	InputId InputGroupId
+
+	Mode CalibrationMode
+
+	clickPlayer *VaryingSpeedPlayer
+
+	// when visual test started
+	visualStart time.Duration
+
+	// how late user tapped from the nearest beat (negative if early)
+	deviations []time.Duration
+}
+
+func NewCalibrationScreen() *CalibrationScreen {
+	cs := new(CalibrationScreen)
+	cs.InputId = NewInputGroupId()
+
+	cs.clickPlayer = NewVaryingSpeedPlayer(0, 0)
+	cs.clickPlayer.LoadDecodedAudio(generateClickTrack(CalibrationInterval(), CalibrationBeats))
+
+	return cs
+}
+
+// Generates beats clicks that are interval apart.
+// Every fourth click has higher pitch.
+func generateClickTrack(interval time.Duration, beats int) []byte {
+	samplesPerBeat := int(i64(interval) * SampleRate / i64(time.Second))
+
+	audio := make([]byte, samplesPerBeat*beats*BytesPerSample)
+
+	click := GenerateClickAudio(1000)
+	accent := GenerateClickAudio(1500)
+
+	for beat := range beats {
+		at := beat * samplesPerBeat * BytesPerSample
+
+		if beat%4 == 0 {
+			copy(audio[at:], accent)
+		} else {
+			copy(audio[at:], click)
+		}
+	}
+
+	return audio
+}
+
+// Returns how far t is from the nearest beat.
+func deviationFromBeat(t time.Duration) time.Duration {
+	interval := CalibrationInterval()
+
+	d := t % interval
+	if d >= interval/2 {
+		d -= interval
+	}
+
+	return d
+}
+
+// Averages deviations after dropping ones that are too far from the median.
+//
+// Returns false if there aren't enough deviations.
+func CalibrationSuggestion(deviations []time.Duration) (time.Duration, bool) {
+	if len(deviations) < CalibrationMinTaps {
+		return 0, false
+	}
+
+	median := func(d []time.Duration) time.Duration {
+		sorted := slices.Clone(d)
+		slices.Sort(sorted)
+		return sorted[len(sorted)/2]
+	}
+
+	m := median(deviations)
+
+	absDevs := make([]time.Duration, len(deviations))
+	for i, d := range deviations {
+		absDevs[i] = AbsI(d - m)
+	}
+
+	// median absolute deviation
+	mad := median(absDevs)
+
+	threshold := max(mad*3, time.Millisecond*15)
+
+	var sum time.Duration
+	count := 0
+
+	for _, d := range deviations {
+		if AbsI(d-m) <= threshold {
+			sum += d
+			count++
+		}
+	}
+
+	if count <= 0 {
+		return 0, false
+	}
+
+	return sum / time.Duration(count), true
+}
+
+func (cs *CalibrationScreen) SetMode(mode CalibrationMode) {
+	cs.Mode = mode
+	cs.deviations = cs.deviations[:0]
+
+	if mode == CalibrationAudio {
+		cs.clickPlayer.SetVolume(1)
+		cs.clickPlayer.Rewind()
+		cs.clickPlayer.Play()
+	} else {
+		cs.clickPlayer.Pause()
+		cs.visualStart = GlobalTimerNow()
+	}
+}
+
+func (cs *CalibrationScreen) tapTime() time.Duration {
+	if cs.Mode == CalibrationAudio {
+		return cs.clickPlayer.Position()
+	}
+
+	return GlobalTimerNow() - cs.visualStart
+}
+
+func (cs *CalibrationScreen) Update(deltaTime time.Duration) {
+	if AreKeysPressed(cs.InputId, TheKM[EscapeKey]) {
+		SetNextScreen(TheOptionsGamePlayScreen)
+		return
+	}
+
+	if AreKeysPressed(cs.InputId, TheKM[PauseKey]) {
+		cs.SetMode((cs.Mode + 1) % CalibrationModeSize)
+	}
+
+	if AreKeysPressed(cs.InputId, TheKM[SongResetKey]) {
+		cs.deviations = cs.deviations[:0]
+	}
+
+	// loop the click track
+	if cs.Mode == CalibrationAudio && !cs.clickPlayer.IsPlaying() {
+		cs.clickPlayer.Rewind()
+		cs.clickPlayer.Play()
+	}
+
+	var noteKeys []int32
+	for _, keys := range NoteKeysArr() {
+		noteKeys = append(noteKeys, keys...)
+	}
+
+	if AreKeysPressed(cs.InputId, noteKeys...) {
+		cs.deviations = append(cs.deviations, deviationFromBeat(cs.tapTime()))
+
+		if len(cs.deviations) > CalibrationMaxTaps {
+			cs.deviations = cs.deviations[len(cs.deviations)-CalibrationMaxTaps:]
+		}
+	}
+
+	if AreKeysPressed(cs.InputId, TheKM[SelectKey]) {
+		if suggestion, ok := CalibrationSuggestion(cs.deviations); ok {
+			cs.applySuggestion(suggestion)
+		}
+	}
+}
+
+// Audio test measures input latency + audio latency
+// which is what AudioOffset and InputOffset should add up to.
+//
+// Visual test measures input latency + display latency
+// which is what InputOffset should be.
+func (cs *CalibrationScreen) applySuggestion(suggestion time.Duration) {
+	if cs.Mode == CalibrationAudio {
+		offset := suggestion - TheOptions.InputOffset
+		TheOptions.AudioOffset = Clamp(offset, 0, AudioOffsetMax)
+
+		alertCalibratedOffset("audio offset", offset, TheOptions.AudioOffset)
+	} else {
+		// keep the total the same so that result of audio test stays valid
+		total := TheOptions.AudioOffset + TheOptions.InputOffset
+
+		TheOptions.InputOffset = Clamp(suggestion, 0, InputOffsetMax)
+		TheOptions.AudioOffset = Clamp(total-TheOptions.InputOffset, 0, AudioOffsetMax)
+
+		alertCalibratedOffset("input offset", suggestion, TheOptions.InputOffset)
+	}
+}
+
+// Tells user what offset was set to
+// and that it was clamped if suggested one was out of range.
+func alertCalibratedOffset(name string, suggested, set time.Duration) {
+	if suggested != set {
+		DisplayAlert(fmt.Sprintf(
+			"suggested %s %dms is out of range, set to %dms",
+			name, suggested.Milliseconds(), set.Milliseconds(),
+		))
+		return
+	}
+
+	DisplayAlert(fmt.Sprintf("%s set to %dms", name, set.Milliseconds()))
+}
+
+func (cs *CalibrationScreen) Draw() {
+	DrawPatternBackground(MenuScreenSimpleBg, 0, 0, ToRlColor(FnfColor{255, 255, 255, 255}))
+
+	black := ToRlColor(FnfColor{0, 0, 0, 255})
+	white := ToRlColor(FnfColor{255, 255, 255, 255})
+
+	// ============================================
+	// draw title
+	// ============================================
+	{
+		const fontSize = 60
+
+		DrawTextOutlined(
+			SdfFontBold, "Offset Calibration", rl.Vector2{60, 30}, fontSize, 0,
+			white, black, 4,
+		)
+
+		var desc string
+		if cs.Mode == CalibrationAudio {
+			desc = "Audio test : tap note keys along to the clicks"
+		} else {
+			desc = "Visual test : tap note keys along to the flashes"
+		}
+
+		DrawTextOutlined(
+			SdfFontBold, desc, rl.Vector2{60, 30 + fontSize}, fontSize*0.5, 0,
+			white, black, 4,
+		)
+	}
+
+	// ============================================
+	// draw beat indicator
+	// ============================================
+	{
+		const size = 160
+
+		rect := rl.Rectangle{
+			X: SCREEN_WIDTH*0.5 - size*0.5, Y: 170,
+			Width: size, Height: size,
+		}
+
+		interval := CalibrationInterval()
+		sinceBeat := cs.tapTime() % interval
+
+		col := FnfColor{0, 0, 0, 100}
+
+		// we don't flash during audio test
+		// so that user doesn't tap along to what they see
+		if cs.Mode == CalibrationVisual && sinceBeat < calibrationFlashDuration {
+			col = FnfColor{255, 255, 255, 255}
+			// every fourth beat is accented
+			if (cs.tapTime()/interval)%4 == 0 {
+				col = FnfColor{0xFF, 0xD1, 0x3B, 0xFF}
+			}
+		}
+
+		rl.DrawRectangleRec(rect, ToRlColor(col))
+		rl.DrawRectangleLinesEx(rect, 4, black)
+	}
+
+	// ============================================
+	// draw taps
+	// ============================================
+	{
+		rect := rl.Rectangle{
+			X: 140, Y: 380,
+			Width: SCREEN_WIDTH - 280, Height: 60,
+		}
+
+		const maxOffset = time.Millisecond * 200
+
+		offsetToX := func(d time.Duration) float32 {
+			t := Clamp(f32(d)/f32(maxOffset), -1, 1)
+			return rect.X + rect.Width*0.5 + t*rect.Width*0.5
+		}
+
+		rl.DrawRectangleRec(rect, ToRlColor(FnfColor{0, 0, 0, 200}))
+
+		centerX := offsetToX(0)
+		rl.DrawLineEx(
+			rl.Vector2{centerX, rect.Y}, rl.Vector2{centerX, rect.Y + rect.Height},
+			2, white,
+		)
+
+		for i, d := range cs.deviations {
+			// recent taps are more opaque
+			alpha := 0.2 + 0.8*f32(i+1)/f32(len(cs.deviations))
+
+			x := offsetToX(d)
+			rl.DrawLineEx(
+				rl.Vector2{x, rect.Y + 8}, rl.Vector2{x, rect.Y + rect.Height - 8},
+				3, ToRlColor(Col01(1, 0.8, 0.2, alpha)),
+			)
+		}
+
+		suggestion, ok := CalibrationSuggestion(cs.deviations)
+
+		if ok {
+			x := offsetToX(suggestion)
+			rl.DrawLineEx(
+				rl.Vector2{x, rect.Y - 10}, rl.Vector2{x, rect.Y + rect.Height + 10},
+				4, ToRlColor(FnfColor{0xFF, 0x4D, 0x4D, 0xFF}),
+			)
+		}
+
+		rl.DrawRectangleLinesEx(rect, 2, white)
+
+		const fontSize = 20
+
+		DrawText(FontClear, fmt.Sprintf("-%dms (early)", maxOffset.Milliseconds()),
+			rl.Vector2{rect.X, rect.Y + rect.Height + 5}, fontSize, 0, black)
+
+		late := fmt.Sprintf("+%dms (late)", maxOffset.Milliseconds())
+		lateSize := MeasureText(FontClear, late, fontSize, 0)
+
+		DrawText(FontClear, late,
+			rl.Vector2{rect.X + rect.Width - lateSize.X, rect.Y + rect.Height + 5}, fontSize, 0, black)
+
+		// draw result
+		const resultFontSize = 40
+
+		var result string
+
+		if !ok {
+			result = fmt.Sprintf("taps : %d/%d", len(cs.deviations), CalibrationMinTaps)
+		} else if cs.Mode == CalibrationAudio {
+			result = fmt.Sprintf(
+				"suggested audio offset : %dms (current %dms)",
+				(suggestion - TheOptions.InputOffset).Milliseconds(),
+				TheOptions.AudioOffset.Milliseconds(),
+			)
+		} else {
+			result = fmt.Sprintf(
+				"suggested input offset : %dms (current %dms)",
+				suggestion.Milliseconds(),
+				TheOptions.InputOffset.Milliseconds(),
+			)
+		}
+
+		resultSize := MeasureText(SdfFontBold, result, resultFontSize, 0)
+
+		DrawTextOutlined(
+			SdfFontBold, result,
+			rl.Vector2{SCREEN_WIDTH*0.5 - resultSize.X*0.5, rect.Y + rect.Height + 50},
+			resultFontSize, 0, white, black, 4,
+		)
+	}
+
+	// ============================================
+	// draw help message
+	// ============================================
+	{
+		const fontSize = 30
+		const margin = 15
+
+		factory := NewRichTextFactory(SCREEN_WIDTH)
+		factory.LineBreakRule = LineBreakNever
+
+		styleBlack := RichTextStyle{
+			FontSize:    fontSize,
+			Font:        SdfFontClear,
+			Fill:        FnfColor{0, 0, 0, 255},
+			Stroke:      FnfColor{255, 255, 255, 255},
+			StrokeWidth: 7,
+		}
+
+		styleRed := styleBlack
+		styleRed.Fill = FnfColor{0xFF, 0x00, 0x00, 0xFF}
+
+		printKey := func(key FnfBinding, desc string) {
+			factory.SetStyle(styleRed)
+			factory.Print(GetKeyName(TheKM[key]))
+
+			factory.SetStyle(styleBlack)
+			factory.Print(desc)
+		}
+
+		printKey(SelectKey, " to apply, ")
+		printKey(SongResetKey, " to clear, ")
+		printKey(PauseKey, fmt.Sprintf(" for %s test, ",
+			CalibrationModeStrs[(cs.Mode+1)%CalibrationModeSize]))
+		printKey(EscapeKey, " to go back")
+
+		elements := factory.Elements(TextAlignLeft, 0, 0)
+		bound := ElementsBound(elements)
+
+		DrawTextElements(elements,
+			SCREEN_WIDTH-bound.Width-margin,
+			SCREEN_HEIGHT-bound.Height-margin,
+			FnfColor{255, 255, 255, 255},
+		)
+	}
+}
+
+func (cs *CalibrationScreen) BeforeScreenTransition() {
+	cs.SetMode(CalibrationAudio)
+}
+
+func (cs *CalibrationScreen) BeforeScreenEnd() {
+	cs.clickPlayer.Pause()
+
+	if err := SaveSettings(); err != nil {
+		ErrorLogger.Printf("failed to save settings %v", err)
+		DisplayAlert("failed to save settings")
+	}
+}
+
+func (cs *CalibrationScreen) Free() {
+}
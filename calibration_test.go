@@ -0,0 +1,56 @@
+package fnf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalibrationSuggestionNeedsEnoughTaps(t *testing.T) {
+	var deviations []time.Duration
+
+	for range CalibrationMinTaps - 1 {
+		deviations = append(deviations, 20*ms)
+
+		if _, ok := CalibrationSuggestion(deviations); ok {
+			t.Fatalf("suggested an offset after only %v taps", len(deviations))
+		}
+	}
+
+	deviations = append(deviations, 20*ms)
+
+	if suggestion, ok := CalibrationSuggestion(deviations); !ok || suggestion != 20*ms {
+		t.Errorf("suggestion = %v, %v, want 20ms", suggestion, ok)
+	}
+}
+
+func TestCalibrationSuggestionAveragesTaps(t *testing.T) {
+	// someone who hits a bit late, but not always by the same amount
+	late := []time.Duration{
+		10 * ms, 12 * ms, 14 * ms, 16 * ms, 18 * ms, 20 * ms, 22 * ms, 24 * ms,
+	}
+
+	if suggestion, _ := CalibrationSuggestion(late); suggestion != 17*ms {
+		t.Errorf("suggestion = %v for late taps, want 17ms", suggestion)
+	}
+
+	// and someone who hits early
+	early := []time.Duration{
+		-30 * ms, -32 * ms, -30 * ms, -32 * ms, -30 * ms, -32 * ms, -30 * ms, -32 * ms,
+	}
+
+	if suggestion, _ := CalibrationSuggestion(early); suggestion != -31*ms {
+		t.Errorf("suggestion = %v for early taps, want -31ms", suggestion)
+	}
+}
+
+func TestCalibrationSuggestionIgnoresStrayTap(t *testing.T) {
+	deviations := []time.Duration{
+		20 * ms, 22 * ms, 18 * ms, 20 * ms,
+		250 * ms, // user got distracted and missed a beat
+		20 * ms, 22 * ms, 18 * ms, 20 * ms,
+	}
+
+	if suggestion, _ := CalibrationSuggestion(deviations); suggestion != 20*ms {
+		t.Errorf("suggestion = %v, want 20ms", suggestion)
+	}
+}
@@ -44,3 +44,89 @@ func (p ProfTimer) Report() {
 	now := time.Now()
 	FnfLogger.Printf("\"%v\" took %v\n", p.Name, now.Sub(p.Start))
 }
+
+const (
+	// if clock is off by more than this, it jumps right to the player position
+	audioClockSnapThreshold = 100 * time.Millisecond
+
+	// how much of the error is corrected each time player position changes
+	audioClockCorrectionRate = 0.1
+)
+
+// Smoothed audio position.
+//
+// Player position only changes when audio buffer is refilled, so it moves in steps.
+// Clock moves along with the global timer instead
+// and is slowly pulled towards the player position whenever it changes.
+type AudioClock struct {
+	anchorPos  time.Duration
+	anchorTime time.Duration
+	speed      float64
+
+	lastPlayerPos time.Duration
+	lastPos       time.Duration
+
+	// how far the clock was from the player position when it last changed
+	Correction time.Duration
+}
+
+func (ac *AudioClock) Reset(pos time.Duration, speed float64) {
+	ac.reset(pos, speed, GlobalTimerNow())
+}
+
+func (ac *AudioClock) reset(pos time.Duration, speed float64, now time.Duration) {
+	ac.anchorPos = pos
+	ac.anchorTime = now
+	ac.speed = speed
+
+	ac.lastPlayerPos = pos
+	ac.lastPos = pos
+
+	ac.Correction = 0
+}
+
+func (ac *AudioClock) estimate(now time.Duration) time.Duration {
+	return ac.anchorPos + time.Duration(f64(now-ac.anchorTime)*ac.speed)
+}
+
+// Returns smoothed position, should be called every frame while audio is playing.
+func (ac *AudioClock) Update(playerPos time.Duration, speed float64) time.Duration {
+	return ac.update(playerPos, speed, GlobalTimerNow())
+}
+
+func (ac *AudioClock) update(playerPos time.Duration, speed float64, now time.Duration) time.Duration {
+	if speed != ac.speed {
+		// start again from here so that clock doesn't jump
+		ac.anchorPos = ac.estimate(now)
+		ac.anchorTime = now
+		ac.speed = speed
+	}
+
+	pos := ac.estimate(now)
+
+	if playerPos != ac.lastPlayerPos {
+		ac.lastPlayerPos = playerPos
+
+		err := playerPos - pos
+		ac.Correction = err
+
+		if AbsI(err) > audioClockSnapThreshold {
+			ac.anchorPos = playerPos
+			ac.anchorTime = now
+			pos = playerPos
+		} else {
+			adjust := time.Duration(f64(err) * audioClockCorrectionRate)
+			ac.anchorPos += adjust
+			pos += adjust
+		}
+	}
+
+	// don't go backwards because of small corrections, notes would shake
+	if pos < ac.lastPos && ac.lastPos-pos < audioClockSnapThreshold {
+		pos = ac.lastPos
+	}
+
+	ac.lastPos = pos
+
+	return pos
+}
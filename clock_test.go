@@ -0,0 +1,70 @@
+package fnf
+
+import (
+	"testing"
+	"time"
+)
+
+func testClockPos(t *testing.T, clock *AudioClock, playerPos time.Duration, speed float64, now, want time.Duration) {
+	t.Helper()
+
+	if pos := clock.update(playerPos, speed, now); AbsI(pos-want) > time.Microsecond {
+		t.Errorf("at %v (player at %v) : position = %v, want %v", now, playerPos, pos, want)
+	}
+}
+
+func testClockCorrection(t *testing.T, clock *AudioClock, want time.Duration) {
+	t.Helper()
+
+	if AbsI(clock.Correction-want) > time.Microsecond {
+		t.Errorf("Correction = %v, want %v", clock.Correction, want)
+	}
+}
+
+// Audio player only reports its position every once in a while,
+// clock should keep moving in between.
+func TestAudioClockMovesBetweenPlayerUpdates(t *testing.T) {
+	var clock AudioClock
+	clock.reset(0, 1, 0)
+
+	testClockPos(t, &clock, 0, 1, 10*ms, 10*ms)
+	testClockPos(t, &clock, 0, 1, 25*ms, 25*ms)
+	testClockCorrection(t, &clock, 0)
+
+	// changing speed shouldn't make notes jump
+	clock.reset(0, 1, 0)
+
+	testClockPos(t, &clock, 0, 2, 100*ms, 100*ms)
+	testClockPos(t, &clock, 0, 2, 200*ms, 300*ms)
+}
+
+func TestAudioClockDriftsTowardPlayer(t *testing.T) {
+	var clock AudioClock
+	clock.reset(0, 1, 0)
+
+	// player is a bit ahead, only move a little toward it so notes don't stutter
+	testClockPos(t, &clock, 20*ms, 1, 10*ms, 11*ms)
+	testClockCorrection(t, &clock, 10*ms)
+
+	// player is a bit behind, wait for it instead of going back
+	clock.reset(0, 1, 0)
+
+	testClockPos(t, &clock, 0, 1, 50*ms, 50*ms)
+	testClockPos(t, &clock, 30*ms, 1, 50*ms, 50*ms)
+	testClockCorrection(t, &clock, -20*ms)
+}
+
+func TestAudioClockSnapsOnBigJumps(t *testing.T) {
+	var clock AudioClock
+	clock.reset(0, 1, 0)
+
+	// like when audio was seeked or stalled
+	testClockPos(t, &clock, 500*ms, 1, 10*ms, 500*ms)
+	testClockPos(t, &clock, 500*ms, 1, 20*ms, 510*ms)
+	testClockCorrection(t, &clock, 490*ms)
+
+	clock.reset(0, 1, 0)
+
+	testClockPos(t, &clock, 10*ms, 1, 500*ms, 10*ms)
+	testClockCorrection(t, &clock, -490*ms)
+}
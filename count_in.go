@@ -0,0 +1,93 @@
+package fnf
+
+import (
+	"fmt"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Count-in plays a measure of metronome clicks before the song starts again
+// so user can get the tempo before notes come.
+
+const (
+	// beats of count-in (a measure)
+	CountInBeats = 4
+
+	// volume of count-in clicks when metronome is turned off
+	CountInDefaultVolume = 0.6
+)
+
+func CountInVolume() float64 {
+	if TheOptions.MetronomeVolume < 0.001 {
+		return CountInDefaultVolume
+	}
+	return TheOptions.MetronomeVolume
+}
+
+// Plays the audio after a measure of clicks if count-in is enabled.
+// Otherwise, plays the audio right away.
+func (gs *GameScreen) PlayAudioWithCountIn() {
+	if !TheOptions.CountIn {
+		gs.PlayAudio()
+		return
+	}
+
+	gs.PauseAudio()
+
+	gs.isCountingIn = true
+	gs.countInStart = GlobalTimerNow()
+	gs.countInClicks = 0
+}
+
+// Length of a count-in beat in real time.
+func (gs *GameScreen) CountInBeatLength() time.Duration {
+	beatLength := BeatsToTime(1, songBpmAt(gs.Song, gs.AudioPosition()))
+	return time.Duration(f64(beatLength) / max(gs.AudioSpeed(), 0.01))
+}
+
+func (gs *GameScreen) DrawCountIn() {
+	const fontSize = 200
+
+	text := fmt.Sprintf("%d", max(gs.countInClicks, 1))
+
+	textSize := MeasureText(SdfFontBold, text, fontSize, 0)
+
+	DrawTextOutlined(
+		SdfFontBold, text,
+		rl.Vector2{SCREEN_WIDTH*0.5 - textSize.X*0.5, SCREEN_HEIGHT*0.5 - textSize.Y*0.5},
+		fontSize, 0,
+		ToRlColor(FnfColor{255, 255, 255, 255}), ToRlColor(FnfColor{0, 0, 0, 255}), 10,
+	)
+}
+
+// Plays count-in clicks and starts the song when count-in is over.
+func (gs *GameScreen) updateCountIn() {
+	if !gs.isCountingIn {
+		return
+	}
+
+	if gs.IsPlayingAudio() {
+		// something else started the song
+		gs.isCountingIn = false
+	} else if gs.DrawMenu {
+		// start over when menu is closed
+		gs.countInStart = GlobalTimerNow()
+		gs.countInClicks = 0
+	} else {
+		beatLength := gs.CountInBeatLength()
+		elapsed := TimeSinceNow(gs.countInStart)
+
+		for gs.countInClicks < CountInBeats &&
+			time.Duration(gs.countInClicks)*beatLength <= elapsed {
+
+			gs.Metronome.PlayClick(gs.countInClicks == 0, CountInVolume())
+			gs.countInClicks++
+		}
+
+		if elapsed >= beatLength*CountInBeats {
+			gs.isCountingIn = false
+			gs.PlayAudio()
+		}
+	}
+}
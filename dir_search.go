@@ -52,6 +52,60 @@ func StringDistance(str1, str2 []byte) int {
 	return matrix[0]
 }
 
+// Returns true if file name (in lower case) is an audio file that song can use.
+//
+// .ogg files are preferred over others when both are present.
+// .opus files are ignored unless app is built with opus support.
+func isSongAudioFile(name string) bool {
+	return strings.HasSuffix(name, ".ogg") ||
+		strings.HasSuffix(name, ".mp3") ||
+		strings.HasSuffix(name, ".flac") ||
+		(OpusSupported && strings.HasSuffix(name, ".opus"))
+}
+
+// Takes voice file names (in lower case, without extension) to their path
+// and figures out whose vocals they are.
+//
+// If there is a voice file for everyone (e.g. Voices.ogg), only that one is used.
+// Otherwise each file is a stem for a character (e.g. Voices-Player.ogg, Voices-bf.ogg).
+func voiceStemsFromPaths(voicePaths map[string]string) []FnfVoiceStem {
+	var stems []FnfVoiceStem
+
+	for name, path := range voicePaths {
+		// what comes after "voices"
+		character := name
+		if i := strings.Index(name, "voice"); i >= 0 {
+			character = name[i+len("voice"):]
+		}
+		character = strings.TrimPrefix(character, "s")
+		character = strings.Trim(character, "-_ ")
+
+		if character == "" {
+			return []FnfVoiceStem{{Path: path, Player: VoiceBothPlayers}}
+		}
+
+		stem := FnfVoiceStem{Path: path, Player: 1}
+
+		if strings.Contains(character, "player") ||
+			strings.Contains(character, "boyfriend") ||
+			character == "bf" || strings.HasPrefix(character, "bf-") {
+			stem.Player = 0
+		}
+
+		stems = append(stems, stem)
+	}
+
+	// player first, map order is random
+	slices.SortFunc(stems, func(a, b FnfVoiceStem) int {
+		if a.Player != b.Player {
+			return int(a.Player - b.Player)
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return stems
+}
+
 // TODO : rather than dumping a log,
 // I think this should really return grouped path
 // like I walked these paths and parsed these paths and so on and so forth...
@@ -74,7 +128,7 @@ func TryToFindSongs(root string, logger *log.Logger) PathGroupCollection {
 			if f.Mode().IsRegular() {
 				name := strings.ToLower(f.Name())
 
-				if strings.HasSuffix(name, ".ogg") || strings.HasSuffix(name, ".mp3") {
+				if isSongAudioFile(name) {
 					audioPaths = append(audioPaths, path)
 				} else if strings.HasSuffix(name, ".json") {
 					jsonPaths = append(jsonPaths, path)
@@ -252,25 +306,31 @@ func TryToFindSongs(root string, logger *log.Logger) PathGroupCollection {
 
 		audioDir := audioDirs[0]
 
+		// voice files without extension to their path
+		voicePaths := make(map[string]string)
+
 		for _, child := range audioDir.Children {
 			childName := strings.ToLower(filepath.Base(child))
+			childStem := strings.TrimSuffix(childName, filepath.Ext(childName))
 
 			if strings.HasSuffix(childName, ".ogg") {
 				if strings.Contains(childName, "inst") {
 					gAndS.Group.InstPath = child
 				} else if strings.Contains(childName, "voice") {
-					gAndS.Group.VoicePath = child
+					voicePaths[childStem] = child
 				}
-			} else if strings.HasSuffix(childName, ".mp3") {
+			} else if isSongAudioFile(childName) {
 				if strings.Contains(childName, "inst") && gAndS.Group.InstPath == "" {
 					gAndS.Group.InstPath = child
-				} else if strings.Contains(childName, "voice") && gAndS.Group.VoicePath == "" {
-					gAndS.Group.VoicePath = child
+				} else if strings.Contains(childName, "voice") && voicePaths[childStem] == "" {
+					voicePaths[childStem] = child
 				}
 			}
 
 		}
 
+		gAndS.Group.Voices = voiceStemsFromPaths(voicePaths)
+
 		gsArray = append(gsArray, gAndS)
 	}
 
@@ -312,7 +372,9 @@ func TryToFindSongs(root string, logger *log.Logger) PathGroupCollection {
 			}
 		}
 		logger.Printf("inst path  : %v\n", group.InstPath)
-		logger.Printf("voice path : %v\n", group.VoicePath)
+		for _, stem := range group.Voices {
+			logger.Printf("voice path : %v (%v)\n", stem.Path, stem.Name())
+		}
 	}
 
 	for _, group := range pathGroups {
@@ -378,7 +440,7 @@ func isPathGroupGood(group FnfPathGroup, songs [DifficultySize]FnfSong) error {
 		}
 	}
 
-	if needsVoices && group.VoicePath == "" {
+	if needsVoices && len(group.Voices) <= 0 {
 		return fmt.Errorf("group %v needs voice but has no voice path", group.SongName)
 	}
 
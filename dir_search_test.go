@@ -0,0 +1,67 @@
+package fnf
+
+import (
+	"slices"
+	"testing"
+)
+
+func testVoiceStems(t *testing.T, voicePaths map[string]string, want ...FnfVoiceStem) {
+	t.Helper()
+
+	if stems := voiceStemsFromPaths(voicePaths); !slices.Equal(stems, want) {
+		t.Errorf("voiceStemsFromPaths(%v) = %v, want %v", voicePaths, stems, want)
+	}
+}
+
+func TestVoiceStemsSingleFile(t *testing.T) {
+	testVoiceStems(t, map[string]string{})
+
+	testVoiceStems(t,
+		map[string]string{"voices": "song/Voices.ogg"},
+		FnfVoiceStem{Path: "song/Voices.ogg", Player: VoiceBothPlayers},
+	)
+
+	// older songs ship both, only play the one that has everyone
+	testVoiceStems(t,
+		map[string]string{
+			"voices":    "song/Voices.ogg",
+			"voices-bf": "song/Voices-bf.ogg",
+		},
+		FnfVoiceStem{Path: "song/Voices.ogg", Player: VoiceBothPlayers},
+	)
+}
+
+func TestVoiceStemsPlayerAndOpponent(t *testing.T) {
+	testVoiceStems(t,
+		map[string]string{
+			"voices-opponent": "song/Voices-Opponent.ogg",
+			"voices-player":   "song/Voices-Player.ogg",
+		},
+		FnfVoiceStem{Path: "song/Voices-Player.ogg", Player: 0},
+		FnfVoiceStem{Path: "song/Voices-Opponent.ogg", Player: 1},
+	)
+}
+
+func TestVoiceStemsCharacterNames(t *testing.T) {
+	// boyfriend and his variants sing player side, everyone else is the opponent
+	testVoiceStems(t,
+		map[string]string{
+			"voices-senpai":   "song/Voices-senpai.ogg",
+			"voices-bf-pixel": "song/Voices-bf-pixel.ogg",
+			"voices-dad":      "song/Voices-dad.ogg",
+		},
+		FnfVoiceStem{Path: "song/Voices-bf-pixel.ogg", Player: 0},
+		FnfVoiceStem{Path: "song/Voices-dad.ogg", Player: 1},
+		FnfVoiceStem{Path: "song/Voices-senpai.ogg", Player: 1},
+	)
+
+	// names that merely start with bf are someone else
+	testVoiceStems(t,
+		map[string]string{
+			"voices_boyfriend": "song/Voices_Boyfriend.ogg",
+			"voices-bfriend":   "song/Voices-bfriend.ogg",
+		},
+		FnfVoiceStem{Path: "song/Voices_Boyfriend.ogg", Player: 0},
+		FnfVoiceStem{Path: "song/Voices-bfriend.ogg", Player: 1},
+	)
+}
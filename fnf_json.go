@@ -82,7 +82,30 @@ func ParseJsonToFnfSong(jsonReader io.Reader) (FnfSong, error) {
 		)
 	}
 
+	// sections don't store when they start
+	// so we have to add up their lengths
+	sectionTime := time.Duration(0)
+	sectionBpm := rawFnfJson.Song.Bpm
+	if sectionBpm <= 0 {
+		sectionBpm = DefaultBpm
+	}
+
 	for _, rawSection := range rawFnfJson.Song.Notes {
+		if rawSection.Bpm > 0 && (rawSection.ChangeBPM || len(parsedSong.Bpms) <= 0) {
+			sectionBpm = rawSection.Bpm
+		}
+
+		parsedSong.Sections = append(parsedSong.Sections, FnfSection{
+			StartsAt: sectionTime,
+			MustHit:  rawSection.MustHitSection,
+		})
+
+		lengthInSteps := rawSection.LengthInSteps
+		if lengthInSteps <= 0 {
+			lengthInSteps = 16
+		}
+		sectionTime += StepsToTime(lengthInSteps, sectionBpm)
+
 		// see if section bpm changes
 		if rawSection.Bpm > 0 {
 			if len(parsedSong.Bpms) <= 0 {
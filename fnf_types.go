@@ -1,6 +1,8 @@
 package fnf
 
 import (
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -99,6 +101,12 @@ type FnfBpm struct {
 
 const DefaultBpm = 100
 
+// Section of a chart as it was written in json file
+type FnfSection struct {
+	StartsAt time.Duration
+	MustHit  bool
+}
+
 type FnfSong struct {
 	SongName    string
 	Notes       []FnfNote
@@ -106,6 +114,7 @@ type FnfSong struct {
 	Speed       float64
 	NeedsVoices bool
 	Bpms        []FnfBpm
+	Sections    []FnfSection
 }
 
 func (fs FnfSong) Copy() FnfSong {
@@ -121,6 +130,11 @@ func (fs FnfSong) Copy() FnfSong {
 		copy.Bpms[i] = fs.Bpms[i]
 	}
 
+	copy.Sections = make([]FnfSection, len(fs.Sections))
+	for i := range len(fs.Sections) {
+		copy.Sections[i] = fs.Sections[i]
+	}
+
 	copy.NotesEndsAt = fs.NotesEndsAt
 	copy.Speed = fs.Speed
 	copy.NeedsVoices = fs.NeedsVoices
@@ -140,6 +154,10 @@ func (fs *FnfSong) OffsetNotesAndBpmChanges(offset time.Duration) {
 	for i := 1; i < len(fs.Bpms); i++ {
 		fs.Bpms[i].StartsAt += offset
 	}
+
+	for i := 0; i < len(fs.Sections); i++ {
+		fs.Sections[i].StartsAt += offset
+	}
 }
 
 func (fs FnfSong) GetBpmAt(at time.Duration) float64 {
@@ -160,6 +178,21 @@ func (fs FnfSong) GetBpmAt(at time.Duration) float64 {
 	return bpm
 }
 
+// Same as GetBpmAt but falls back to DefaultBpm when chart has no valid bpm,
+// so stepping through the song by beats always moves forward.
+func songBpmAt(song FnfSong, at time.Duration) float64 {
+	if len(song.Bpms) <= 0 {
+		return DefaultBpm
+	}
+
+	bpm := song.GetBpmAt(at)
+	if bpm <= 0 {
+		return DefaultBpm
+	}
+
+	return bpm
+}
+
 type FnfDifficulty int
 
 const (
@@ -177,18 +210,43 @@ var DifficultyStrs [DifficultySize]string = [DifficultySize]string{
 
 type FnfPathGroupId int64
 
+// Player that voice stem with every player's vocals belongs to.
+const VoiceBothPlayers FnfPlayerNo = -1
+
+// Vocal track of a song.
+//
+// Some songs have a single track for every vocal,
+// others have a separate one for each character.
+type FnfVoiceStem struct {
+	Path string
+
+	// whose vocals they are, VoiceBothPlayers if they are everyone's
+	Player FnfPlayerNo
+}
+
 type FnfPathGroup struct {
 	SongName string
 
 	SongPaths [DifficultySize]string
 	HasSong   [DifficultySize]bool
 
-	InstPath  string
-	VoicePath string
+	InstPath string
+
+	Voices []FnfVoiceStem
+
+	// NOTE : collections saved before voice stems were added only have this.
+	// It's moved to Voices when collections are loaded, so use Voices instead.
+	VoicePath string `json:",omitempty"`
 
 	id FnfPathGroupId
 }
 
+// Name of the voice stem to show to user (file name without extension).
+func (stem FnfVoiceStem) Name() string {
+	name := filepath.Base(stem.Path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
 var fnfPathGroupIdGenerator IdGenerator[FnfPathGroupId]
 
 func NewFnfPathGroupId() FnfPathGroupId {
@@ -233,6 +291,12 @@ var RatingStrs [HitRatingSize]string = [HitRatingSize]string{
 	"sick",
 }
 
+var RatingColors [HitRatingSize]FnfColor = [HitRatingSize]FnfColor{
+	FnfColor{0xDA, 0xA5, 0x20, 0xFF},
+	FnfColor{0x57, 0xE3, 0x13, 0xFF},
+	FnfColor{0x32, 0xBC, 0xE7, 0xFF},
+}
+
 func GetHitRating(noteStartsAt time.Duration, noteHitAt time.Duration) FnfHitRating {
 	t := AbsI(noteStartsAt - noteHitAt)
 
@@ -28,19 +28,27 @@ func _() {
 	_ = x[AudioOffsetDownKey-17]
 	_ = x[SetBookMarkKey-18]
 	_ = x[JumpToBookMarkKey-19]
-	_ = x[ZoomOutKey-20]
-	_ = x[ZoomInKey-21]
-	_ = x[ScreenshotKey-22]
-	_ = x[ToggleDebugMsg-23]
-	_ = x[ToggleLogNoteEvent-24]
-	_ = x[ToggleDebugGraphics-25]
-	_ = x[ReloadAssetsKey-26]
-	_ = x[FnfBindingSize-27]
+	_ = x[PrevBookMarkKey-20]
+	_ = x[NextBookMarkKey-21]
+	_ = x[RenameBookMarkKey-22]
+	_ = x[LoopStartKey-23]
+	_ = x[LoopEndKey-24]
+	_ = x[ClearLoopKey-25]
+	_ = x[NextTroubleSpotKey-26]
+	_ = x[ReviewModeKey-27]
+	_ = x[ZoomOutKey-28]
+	_ = x[ZoomInKey-29]
+	_ = x[ScreenshotKey-30]
+	_ = x[ToggleDebugMsg-31]
+	_ = x[ToggleLogNoteEvent-32]
+	_ = x[ToggleDebugGraphics-33]
+	_ = x[ReloadAssetsKey-34]
+	_ = x[FnfBindingSize-35]
 }
 
-const _FnfBinding_name = "NoteKeyLeft0NoteKeyLeft1NoteKeyDown0NoteKeyDown1NoteKeyUp0NoteKeyUp1NoteKeyRight0NoteKeyRight1SelectKeyPauseKeyEscapeKeySongResetKeyNoteScrollUpKeyNoteScrollDownKeyAudioSpeedUpKeyAudioSpeedDownKeyAudioOffsetUpKeyAudioOffsetDownKeySetBookMarkKeyJumpToBookMarkKeyZoomOutKeyZoomInKeyScreenshotKeyToggleDebugMsgToggleLogNoteEventToggleDebugGraphicsReloadAssetsKeyFnfBindingSize"
+const _FnfBinding_name = "NoteKeyLeft0NoteKeyLeft1NoteKeyDown0NoteKeyDown1NoteKeyUp0NoteKeyUp1NoteKeyRight0NoteKeyRight1SelectKeyPauseKeyEscapeKeySongResetKeyNoteScrollUpKeyNoteScrollDownKeyAudioSpeedUpKeyAudioSpeedDownKeyAudioOffsetUpKeyAudioOffsetDownKeySetBookMarkKeyJumpToBookMarkKeyPrevBookMarkKeyNextBookMarkKeyRenameBookMarkKeyLoopStartKeyLoopEndKeyClearLoopKeyNextTroubleSpotKeyReviewModeKeyZoomOutKeyZoomInKeyScreenshotKeyToggleDebugMsgToggleLogNoteEventToggleDebugGraphicsReloadAssetsKeyFnfBindingSize"
 
-var _FnfBinding_index = [...]uint16{0, 12, 24, 36, 48, 58, 68, 81, 94, 103, 111, 120, 132, 147, 164, 179, 196, 212, 230, 244, 261, 271, 280, 293, 307, 325, 344, 359, 373}
+var _FnfBinding_index = [...]uint16{0, 12, 24, 36, 48, 58, 68, 81, 94, 103, 111, 120, 132, 147, 164, 179, 196, 212, 230, 244, 261, 276, 291, 308, 320, 330, 342, 360, 373, 383, 392, 405, 419, 437, 456, 471, 485}
 
 func (i FnfBinding) String() string {
 	if i < 0 || i >= FnfBinding(len(_FnfBinding_index)-1) {
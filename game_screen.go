@@ -1,15 +1,19 @@
 package fnf
 
 import (
+	"cmp"
 	_ "embed"
+	"errors"
 	"fmt"
 	_ "image/jpeg"
 	_ "image/png"
 	"math"
 	"math/rand/v2"
+	"slices"
 	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/sqweek/dialog"
 )
 
 type NotePopup struct {
@@ -128,7 +132,7 @@ var GSC struct {
 func init() {
 	GSC.PadStart = time.Millisecond * 500  // 0.5 seconds
 	GSC.StopAfter = time.Millisecond * 100 // 0.1 seconds
-	GSC.PadEnd = AudioOffsetMax + GSC.StopAfter
+	GSC.PadEnd = AudioOffsetMax + InputOffsetMax + GSC.StopAfter
 
 	GSC.NotesMarginLeft = 145
 	GSC.NotesMarginRight = 145
@@ -163,8 +167,11 @@ type GameScreen struct {
 	Song         FnfSong
 	IsSongLoaded bool
 
-	InstPlayer  *VaryingSpeedPlayer
-	VoicePlayer *VaryingSpeedPlayer
+	InstPlayer *VaryingSpeedPlayer
+
+	// one for each voice stem of the song
+	VoicePlayers []*VaryingSpeedPlayer
+	VoiceStems   []FnfVoiceStem
 
 	Pstates [FnfPlayerSize]PlayerState
 
@@ -179,15 +186,60 @@ type GameScreen struct {
 
 	SplashQueue CircularQueue[NoteSplash]
 
+	HitErrorBar *HitErrorBar
+
+	Heatmap ProgressHeatmap
+
 	HelpMessage *GameHelpMessage
 
 	AudioSpeedSetAt  time.Duration
 	ZoomSetAt        time.Duration
 	AudioOffsetSetAt time.Duration
 
+	// currently selected bookmark
+	// this is what JumpToBookMarkKey and rewind uses
 	BookMark    time.Duration
 	BookMarkSet bool
 
+	// every bookmarks, sorted by time
+	BookMarks     []SongBookMark
+	BookMarkIndex int
+
+	// a-b loop
+	LoopStart    time.Duration
+	LoopEnd      time.Duration
+	LoopStartSet bool
+	LoopEndSet   bool
+
+	// how many times we went through the loop
+	LoopCount int
+	// accuracy of each pass (0 to 1)
+	LoopAccuracies []float64
+
+	// passes of the current loop, added to play history when loop changes
+	loopRecord    PlayRecord
+	loopRecordKey string
+
+	SpeedTrainer SpeedTrainer
+
+	// mistakes user made since song was loaded
+	// unlike NoteEvents, these are not cleared when game state is reset
+	TroubleEvents []TroubleEvent
+	TroubleSpots  []TroubleSpot
+
+	troubleSpotIndex int
+	// index used by NextTroubleSpotKey
+	troubleSpotKeyIndex int
+
+	// hash of each difficulty's chart, from ChartHash()
+	ChartHashes [DifficultySize]string
+
+	// not nil while we are playing a replay
+	ReplayPlayer *ReplayPlayer
+
+	// personal best run, nil if there isn't one
+	Ghost *Ghost
+
 	LogNoteEvent bool
 
 	RewindOnMistake bool
@@ -203,8 +255,27 @@ type GameScreen struct {
 	BotPlayMenuItemId         MenuItemId
 	DifficultyMenuItemId      MenuItemId
 	RewindOnMistakeMenuItemId MenuItemId
+	SpeedTrainerMenuItemId    MenuItemId
+
+	TroubleSpotsMenuItemId    MenuItemId
+	TroubleSpotJumpMenuItemId MenuItemId
+	TroubleSpotLoopMenuItemId MenuItemId
 	OpponentModeMenuItemId    MenuItemId
 
+	StopReplayMenuItemId MenuItemId
+
+	ClearSongOverrideMenuItemId MenuItemId
+
+	MixerVolumeMenuItemIds [MixerTrackSize]MenuItemId
+	MixerMuteMenuItemIds   [MixerTrackSize]MenuItemId
+
+	// one for each voice stem
+	VoiceMuteMenuItemIds []MenuItemId
+
+	MuteOwnVoiceMenuItemId MenuItemId
+
+	PathGroup FnfPathGroup
+
 	// private members
 	isKeyPressed   [FnfPlayerSize][NoteDirSize]bool
 	noteIndexStart int
@@ -212,8 +283,34 @@ type GameScreen struct {
 	tempPauseFrameCounter   int
 	wasPlayingWhenTempPause bool
 
-	audioPosition      time.Duration
-	prevPlayerPosition time.Duration
+	// count-in before song resumes
+	Metronome             *Metronome
+	isCountingIn          bool
+	countInStart          time.Duration
+	countInClicks         int
+	countInAfterTempPause bool
+
+	// so that we only tell user once
+	reportedDecodingError bool
+
+	// players are reused when we load another song
+	voicePlayerPool []*VaryingSpeedPlayer
+	voiceMuted      []bool
+
+	// mute voice stems of whoever user is playing as
+	muteOwnVoice bool
+
+	audioPosition time.Duration
+
+	// smoothed inst player position
+	audioClock AudioClock
+
+	// how far each voice player is ahead of inst player, summed up until next check
+	voiceDriftSum       []time.Duration
+	voiceDriftSamples   int
+	voiceDriftCheckedAt time.Duration
+	// biggest correction we made at last check
+	voiceDriftCorrection time.Duration
 
 	positionChangedWhilePaused bool
 
@@ -224,6 +321,9 @@ type GameScreen struct {
 	// progress bar
 	isProgressBarInFocus bool
 
+	isLoopDragging bool
+	loopDragFrom   time.Duration
+
 	// rewind stuff
 	rewindQueue      CircularQueue[AnimatedRewind]
 	rewindT          float64
@@ -237,8 +337,30 @@ type GameScreen struct {
 
 	// we need multiple hit sound players because if we only have one,
 	// that one player might be busy when we need to play another hit sound
-	hitSoundPlayers     []*VaryingSpeedPlayer
-	hitSoundPlayerIndex int
+
+	bookMarkNameInput *TextInputBox
+
+	// true while we are at result screen
+	// we don't want to reset anything when we come back
+	isShowingResult bool
+
+	// in review mode, song stays paused
+	// and notes are drawn with how user played them
+	IsReviewing bool
+
+	// key presses and releases of current run
+	replayEvents []ReplayEvent
+
+	// states before we started playing a replay
+	hitWindowsBeforeReplay   [HitRatingSize]time.Duration
+	opponentModeBeforeReplay bool
+	audioSpeedBeforeReplay   float64
+
+	// options that song override replaced
+	// restored when we leave the song
+	isSongOverrideApplied     bool
+	audioOffsetBeforeOverride time.Duration
+	downScrollBeforeOverride  bool
 }
 
 func NewGameScreen() *GameScreen {
@@ -248,7 +370,8 @@ func NewGameScreen() *GameScreen {
 	gs.zoom = 1.0
 
 	gs.InstPlayer = NewVaryingSpeedPlayer(GSC.PadStart, GSC.PadEnd)
-	gs.VoicePlayer = NewVaryingSpeedPlayer(GSC.PadStart, GSC.PadEnd)
+
+	gs.InstPlayer.SetMixerTrack(MixerTrackInst)
 
 	gs.PopupQueue = CircularQueue[NotePopup]{
 		Data: make([]NotePopup, 128), // 128 popups should be enough for everyone right?
@@ -258,6 +381,8 @@ func NewGameScreen() *GameScreen {
 		Data: make([]NoteSplash, 256), // 256 splashes should be enough for everyone right?
 	}
 
+	gs.HitErrorBar = NewHitErrorBar()
+
 	gs.rewindQueue = CircularQueue[AnimatedRewind]{
 		Data: make([]AnimatedRewind, 8),
 	}
@@ -268,29 +393,13 @@ func NewGameScreen() *GameScreen {
 
 	gs.HelpMessage = NewGameHelpMessage(gs.InputId)
 
-	for i := 0; i < 32; i++ {
-		gs.hitSoundPlayers = append(gs.hitSoundPlayers, NewVaryingSpeedPlayer(0, 0))
-	}
+	gs.bookMarkNameInput = NewTextInputBox()
 
-	// load hit sound
-	for _, player := range gs.hitSoundPlayers {
-		player.LoadDecodedAudio(HitSoundAudio)
-	}
+	gs.Metronome = NewMetronome()
 
 	// set up menu
 	gs.Menu = NewMenuDrawer()
 	{
-		whiteMenuItem := func() *MenuItem {
-			const fade = 0.5
-			alpha := fade * 255
-
-			item := NewMenuItem()
-			item.Color = FnfColor{255, 255, 255, uint8(alpha)}
-			item.ColorSelected = FnfColor{255, 255, 255, 255}
-			item.Fade = fade
-			return item
-		}
-
 		resumeItem := whiteMenuItem()
 		resumeItem.Type = MenuItemTrigger
 		resumeItem.Name = "Resume"
@@ -308,6 +417,19 @@ func NewGameScreen() *GameScreen {
 		gs.RewindOnMistakeMenuItemId = rewindItem.Id
 		gs.Menu.AddItems(rewindItem)
 
+		speedTrainerItem := whiteMenuItem()
+		speedTrainerItem.Type = MenuItemToggle
+		speedTrainerItem.Name = "Speed Trainer"
+		speedTrainerItem.ToggleCallback = func(bValue bool) {
+			gs.SpeedTrainer.Enabled = bValue
+			gs.SpeedTrainer.Reset()
+			if bValue {
+				gs.SetAudioSpeed(gs.SpeedTrainer.Speed)
+			}
+		}
+		gs.SpeedTrainerMenuItemId = speedTrainerItem.Id
+		gs.Menu.AddItems(speedTrainerItem)
+
 		opponentModeItem := whiteMenuItem()
 		opponentModeItem.Type = MenuItemToggle
 		opponentModeItem.Name = "Opponent Mode"
@@ -320,12 +442,154 @@ func NewGameScreen() *GameScreen {
 		gs.BotPlayMenuItemId = botPlayItem.Id
 		gs.Menu.AddItems(botPlayItem)
 
+		troubleSpotsItem := whiteMenuItem()
+		troubleSpotsItem.Type = MenuItemList
+		troubleSpotsItem.Name = "Trouble Spots"
+		troubleSpotsItem.ListCallback = func(selected int, list []string) {
+			gs.troubleSpotIndex = selected
+		}
+		gs.TroubleSpotsMenuItemId = troubleSpotsItem.Id
+		gs.Menu.AddItems(troubleSpotsItem)
+
+		troubleSpotJumpItem := whiteMenuItem()
+		troubleSpotJumpItem.Type = MenuItemTrigger
+		troubleSpotJumpItem.Name = "Jump To Trouble Spot"
+		troubleSpotJumpItem.TriggerCallback = func() {
+			gs.JumpToTroubleSpot(gs.troubleSpotIndex)
+			gs.DrawMenu = false
+		}
+		gs.TroubleSpotJumpMenuItemId = troubleSpotJumpItem.Id
+		gs.Menu.AddItems(troubleSpotJumpItem)
+
+		troubleSpotLoopItem := whiteMenuItem()
+		troubleSpotLoopItem.Type = MenuItemTrigger
+		troubleSpotLoopItem.Name = "Loop Trouble Spot"
+		troubleSpotLoopItem.TriggerCallback = func() {
+			gs.LoopTroubleSpot(gs.troubleSpotIndex)
+			gs.DrawMenu = false
+		}
+		gs.TroubleSpotLoopMenuItemId = troubleSpotLoopItem.Id
+		gs.Menu.AddItems(troubleSpotLoopItem)
+
+		loadReplayItem := whiteMenuItem()
+		loadReplayItem.Type = MenuItemTrigger
+		loadReplayItem.Name = "Load Replay"
+		loadReplayItem.TriggerCallback = func() {
+			gs.PauseAudio()
+
+			ShowTransition(BlackPixel, func() {
+				defer HideTransition()
+
+				builder := dialog.File().Title("Select Replay").Filter("replay file", "json")
+				if dir, err := ReplaysDir(); err == nil {
+					builder = builder.SetStartDir(dir)
+				}
+
+				path, err := builder.Load()
+				if err != nil && !errors.Is(err, dialog.ErrCancelled) {
+					ErrorLogger.Printf("failed to open replay: %v", err)
+					DisplayAlert("failed to open replay")
+					return
+				}
+
+				if errors.Is(err, dialog.ErrCancelled) {
+					return
+				}
+
+				replay, err := LoadReplay(path)
+				if err != nil {
+					ErrorLogger.Printf("failed to load replay: %v", err)
+					DisplayAlert("failed to load replay")
+					return
+				}
+
+				if err := gs.StartReplay(replay); err != nil {
+					ErrorLogger.Printf("failed to start replay: %v", err)
+					DisplayAlert(err.Error())
+					return
+				}
+
+				gs.DrawMenu = false
+			})
+		}
+		gs.Menu.AddItems(loadReplayItem)
+
+		stopReplayItem := whiteMenuItem()
+		stopReplayItem.Type = MenuItemTrigger
+		stopReplayItem.Name = "Stop Replay"
+		stopReplayItem.TriggerCallback = func() {
+			gs.StopReplay()
+			gs.DrawMenu = false
+		}
+		gs.StopReplayMenuItemId = stopReplayItem.Id
+		gs.Menu.AddItems(stopReplayItem)
+
 		difficultyItem := whiteMenuItem()
 		difficultyItem.Type = MenuItemList
 		difficultyItem.Name = "Difficulty"
 		gs.DifficultyMenuItemId = difficultyItem.Id
 		gs.Menu.AddItems(difficultyItem)
 
+		// mixer
+		for track := MixerTrack(0); track < MixerTrackSize; track++ {
+			volumeItem := whiteMenuItem()
+			volumeItem.Type = MenuItemNumber
+			volumeItem.Name = MixerTrackNames[track] + " Volume"
+			volumeItem.NValueMin = 0
+			volumeItem.NValueMax = 10
+			volumeItem.NValueInterval = 1
+			volumeItem.NValueFmtString = "%1.f"
+			volumeItem.NumberCallback = func(nValue float32) {
+				TheOptions.Mixer[track].Volume = f64(nValue) / 10
+			}
+			gs.MixerVolumeMenuItemIds[track] = volumeItem.Id
+			gs.Menu.AddItems(volumeItem)
+
+			muteItem := whiteMenuItem()
+			muteItem.Type = MenuItemToggle
+			muteItem.Name = "Mute " + MixerTrackNames[track]
+			muteItem.ToggleCallback = func(bValue bool) {
+				TheOptions.Mixer[track].Mute = bValue
+			}
+			gs.MixerMuteMenuItemIds[track] = muteItem.Id
+			gs.Menu.AddItems(muteItem)
+		}
+
+		muteOwnVoiceItem := whiteMenuItem()
+		muteOwnVoiceItem.Type = MenuItemToggle
+		muteOwnVoiceItem.Name = "Mute Your Voice"
+		muteOwnVoiceItem.ToggleCallback = func(bValue bool) {
+			gs.muteOwnVoice = bValue
+			gs.updateVoiceMutes()
+		}
+		gs.MuteOwnVoiceMenuItemId = muteOwnVoiceItem.Id
+		gs.Menu.AddItems(muteOwnVoiceItem)
+
+		saveSongOverrideItem := whiteMenuItem()
+		saveSongOverrideItem.Type = MenuItemTrigger
+		saveSongOverrideItem.Name = "Save Settings For Song"
+		saveSongOverrideItem.TriggerCallback = func() {
+			gs.saveSongOverride(false)
+		}
+		gs.Menu.AddItems(saveSongOverrideItem)
+
+		saveDifficultyOverrideItem := whiteMenuItem()
+		saveDifficultyOverrideItem.Type = MenuItemTrigger
+		saveDifficultyOverrideItem.Name = "Save Settings For Difficulty"
+		saveDifficultyOverrideItem.TriggerCallback = func() {
+			gs.saveSongOverride(true)
+		}
+		gs.Menu.AddItems(saveDifficultyOverrideItem)
+
+		clearSongOverrideItem := whiteMenuItem()
+		clearSongOverrideItem.Type = MenuItemTrigger
+		clearSongOverrideItem.Name = "Clear Song Settings"
+		clearSongOverrideItem.TriggerCallback = func() {
+			gs.clearSongOverride()
+		}
+		gs.ClearSongOverrideMenuItemId = clearSongOverrideItem.Id
+		gs.Menu.AddItems(clearSongOverrideItem)
+
 		quitItem := whiteMenuItem()
 		quitItem.Type = MenuItemTrigger
 		quitItem.Name = "Return To Menu"
@@ -344,21 +608,104 @@ func NewGameScreen() *GameScreen {
 	return gs
 }
 
+// Menu item used in pause menu.
+func whiteMenuItem() *MenuItem {
+	const fade = 0.5
+	alpha := fade * 255
+
+	item := NewMenuItem()
+	item.Color = FnfColor{255, 255, 255, uint8(alpha)}
+	item.ColorSelected = FnfColor{255, 255, 255, 255}
+	item.Fade = fade
+	return item
+}
+
+// Replaces mute toggles in pause menu with ones for current voice stems.
+//
+// They are only shown when song has more than one voice stem,
+// since mixer can already mute a single one.
+func (gs *GameScreen) updateVoiceMuteMenuItems() {
+	gs.Menu.DeleteItems(gs.VoiceMuteMenuItemIds...)
+	gs.VoiceMuteMenuItemIds = gs.VoiceMuteMenuItemIds[:0]
+
+	if len(gs.VoiceStems) <= 1 {
+		return
+	}
+
+	// put them after mixer
+	insertAt := slices.Index(gs.Menu.GetItemIds(), gs.MuteOwnVoiceMenuItemId) + 1
+
+	var items []*MenuItem
+
+	for i, stem := range gs.VoiceStems {
+		item := whiteMenuItem()
+		item.Type = MenuItemToggle
+		item.Name = "Mute " + stem.Name()
+		item.ToggleCallback = func(bValue bool) {
+			gs.SetVoiceMuted(i, bValue)
+		}
+
+		items = append(items, item)
+		gs.VoiceMuteMenuItemIds = append(gs.VoiceMuteMenuItemIds, item.Id)
+	}
+
+	gs.Menu.InsertAt(insertAt, items...)
+}
+
+func (gs *GameScreen) SetVoiceMuted(stem int, muted bool) {
+	if stem < 0 || stem >= len(gs.VoicePlayers) {
+		return
+	}
+
+	gs.voiceMuted[stem] = muted
+	gs.updateVoiceMutes()
+}
+
+// Returns true if song has separate voice stems for each player.
+func (gs *GameScreen) hasVoiceStemsPerPlayer() bool {
+	for _, stem := range gs.VoiceStems {
+		if stem.Player != VoiceBothPlayers {
+			return true
+		}
+	}
+	return false
+}
+
+// Mutes voice players that are muted by user
+// or belong to whoever user is playing as if muteOwnVoice is set.
+//
+// Called every frame since main player changes with opponent mode.
+func (gs *GameScreen) updateVoiceMutes() {
+	for i, player := range gs.VoicePlayers {
+		muted := gs.voiceMuted[i]
+
+		if gs.muteOwnVoice && gs.VoiceStems[i].Player == gs.mainPlayer() {
+			muted = true
+		}
+
+		player.SetMuted(muted)
+	}
+}
+
 func (gs *GameScreen) LoadSongs(
+	group FnfPathGroup,
 	songs [DifficultySize]FnfSong,
-	hasSong [DifficultySize]bool,
 	startingDifficulty FnfDifficulty,
-	instBytes, voiceBytes []byte,
-	instType, voiceType string,
+	instBytes []byte, voiceBytes [][]byte,
+	instType string, voiceTypes []string,
 ) error {
 	gs.IsSongLoaded = true
 
+	hasSong := group.HasSong
+
+	gs.PathGroup = group
 	gs.HasSong = hasSong
 	gs.SelectedDifficulty = startingDifficulty
 
 	for i := FnfDifficulty(0); i < DifficultySize; i++ {
 		if hasSong[i] {
 			gs.Songs[i] = songs[i].Copy()
+			gs.ChartHashes[i] = ChartHash(songs[i])
 		}
 	}
 
@@ -368,29 +715,75 @@ func (gs *GameScreen) LoadSongs(
 	}
 
 	gs.SetSong(gs.Songs[startingDifficulty])
+	gs.loadBookMarks()
+	gs.loadGhost()
 
 	if gs.InstPlayer.IsReady() {
 		gs.InstPlayer.Pause()
 	}
 
-	if gs.VoicePlayer.IsReady() {
-		gs.VoicePlayer.Pause()
+	for _, player := range gs.voicePlayerPool {
+		if player.IsReady() {
+			player.Pause()
+			player.QuitBackgroundDecoding()
+		}
 	}
 
 	if err := gs.InstPlayer.LoadAudio(instBytes, instType, TheOptions.LoadAudioDuringGamePlay); err != nil {
 		return err
 	}
+
+	gs.VoicePlayers = gs.VoicePlayers[:0]
+	gs.VoiceStems = gs.VoiceStems[:0]
+
 	if gs.Song.NeedsVoices {
-		if err := gs.VoicePlayer.LoadAudio(voiceBytes, voiceType, TheOptions.LoadAudioDuringGamePlay); err != nil {
-			return err
+		for i, stem := range group.Voices {
+			if i >= len(voiceBytes) {
+				break
+			}
+
+			if i >= len(gs.voicePlayerPool) {
+				player := NewVaryingSpeedPlayer(GSC.PadStart, GSC.PadEnd)
+				player.SetMixerTrack(MixerTrackVoice)
+				gs.voicePlayerPool = append(gs.voicePlayerPool, player)
+			}
+
+			player := gs.voicePlayerPool[i]
+
+			if err := player.LoadAudio(voiceBytes[i], voiceTypes[i], TheOptions.LoadAudioDuringGamePlay); err != nil {
+				return err
+			}
+
+			gs.VoicePlayers = append(gs.VoicePlayers, player)
+			gs.VoiceStems = append(gs.VoiceStems, stem)
 		}
 	}
 
+	// free audio of stems that previous song had but this one doesn't
+	for _, player := range gs.voicePlayerPool[len(gs.VoicePlayers):] {
+		player.unload()
+	}
+
+	gs.voiceMuted = make([]bool, len(gs.VoicePlayers))
+	gs.updateVoiceMuteMenuItems()
+	gs.updateVoiceMutes()
+
+	gs.Metronome.LoadSong(gs.Song, gs.AudioDuration())
+
+	gs.reportedDecodingError = false
+
 	gs.InstPlayer.SetSpeed(1)
-	if gs.Song.NeedsVoices {
-		gs.VoicePlayer.SetSpeed(1)
+	for _, player := range gs.VoicePlayers {
+		player.SetVolume(1)
+	}
+	for _, player := range gs.followingPlayers() {
+		player.SetSpeed(1)
 	}
 
+	gs.zoom = 1.0
+
+	gs.applySongOverride()
+
 	gs.SetAudioPositionNoOffset(0)
 
 	return nil
@@ -399,11 +792,17 @@ func (gs *GameScreen) LoadSongs(
 func (gs *GameScreen) SetSong(song FnfSong) {
 	gs.Song = song.Copy()
 
+	gs.TroubleEvents = gs.TroubleEvents[:0]
+	gs.TroubleSpots = gs.TroubleSpots[:0]
+	gs.troubleSpotKeyIndex = -1
+
 	gs.NoteEvents = make([][]NoteEvent, len(gs.Song.Notes))
 	for i := range len(gs.NoteEvents) {
 		gs.NoteEvents[i] = make([]NoteEvent, 0, 8) // completely arbitrary number
 	}
 
+	gs.Heatmap.MarkSongChanged()
+
 	gs.ResetGameStates()
 }
 
@@ -426,9 +825,36 @@ func (gs *GameScreen) PlayAudio() {
 	}
 
 	gs.InstPlayer.Play()
-	if gs.VoicePlayer.IsReady() && gs.Song.NeedsVoices {
-		gs.VoicePlayer.Play()
+	for _, player := range gs.followingPlayers() {
+		player.Play()
+	}
+}
+
+// Returns players that should follow inst player (voices and metronome).
+func (gs *GameScreen) followingPlayers() []*VaryingSpeedPlayer {
+	players := gs.VoicePlayers[:len(gs.VoicePlayers):len(gs.VoicePlayers)]
+
+	if gs.Metronome.SongPlayer != nil && gs.Metronome.SongPlayer.IsReady() {
+		players = append(players, gs.Metronome.SongPlayer)
 	}
+
+	return players
+}
+
+// Moves metronome clicks to beats of the current song.
+func (gs *GameScreen) reloadMetronome() {
+	gs.Metronome.LoadSong(gs.Song, gs.AudioDuration())
+
+	player := gs.Metronome.SongPlayer
+
+	player.SetSpeed(gs.AudioSpeed())
+	player.SetPosition(gs.AudioPositionNoOffset())
+
+	if gs.IsPlayingAudio() {
+		player.Play()
+	}
+
+	gs.resetVoiceDrift()
 }
 
 func (gs *GameScreen) PauseAudio() {
@@ -440,11 +866,77 @@ func (gs *GameScreen) PauseAudio() {
 	if gs.InstPlayer.IsReady() {
 		gs.InstPlayer.Pause()
 	}
-	if gs.VoicePlayer.IsReady() {
-		gs.VoicePlayer.Pause()
+	for _, player := range gs.followingPlayers() {
+		player.Pause()
 	}
 }
 
+const (
+	// how often we check if voices drifted away from inst
+	voiceDriftCheckInterval = time.Second
+
+	// voices are moved back in sync when they drift more than this
+	voiceDriftMax = 15 * time.Millisecond
+)
+
+func (gs *GameScreen) resetVoiceDrift() {
+	gs.voiceDriftSum = gs.voiceDriftSum[:0]
+	for range gs.followingPlayers() {
+		gs.voiceDriftSum = append(gs.voiceDriftSum, 0)
+	}
+
+	gs.voiceDriftSamples = 0
+	gs.voiceDriftCheckedAt = GlobalTimerNow()
+}
+
+// Moves voice and metronome players back in sync with inst player if they drifted apart.
+//
+// Player positions are only accurate to the size of audio buffer,
+// so we look at the average over voiceDriftCheckInterval.
+func (gs *GameScreen) correctVoiceDrift(instPos time.Duration) {
+	players := gs.followingPlayers()
+
+	if len(gs.voiceDriftSum) != len(players) {
+		gs.resetVoiceDrift()
+	}
+
+	// voices might be shorter than inst, their position stays at the end once they are done
+	hasEnded := func(player *VaryingSpeedPlayer) bool {
+		return !player.IsPlaying() || player.Position() >= player.AudioDuration()
+	}
+
+	for i, player := range players {
+		if !hasEnded(player) {
+			gs.voiceDriftSum[i] += player.Position() - instPos
+		}
+	}
+	gs.voiceDriftSamples++
+
+	if TimeSinceNow(gs.voiceDriftCheckedAt) < voiceDriftCheckInterval {
+		return
+	}
+
+	gs.voiceDriftCorrection = 0
+
+	for i, player := range players {
+		if hasEnded(player) {
+			continue
+		}
+
+		drift := gs.voiceDriftSum[i] / time.Duration(gs.voiceDriftSamples)
+
+		if AbsI(drift) > voiceDriftMax {
+			player.SetPosition(player.Position() - drift)
+
+			if AbsI(drift) > AbsI(gs.voiceDriftCorrection) {
+				gs.voiceDriftCorrection = drift
+			}
+		}
+	}
+
+	gs.resetVoiceDrift()
+}
+
 func (gs *GameScreen) AudioPositionNoOffset() time.Duration {
 	if !gs.IsSongLoaded {
 		ErrorLogger.Printf("GameScreen: Called when song is not loaded")
@@ -458,6 +950,16 @@ func (gs *GameScreen) AudioPosition() time.Duration {
 	return gs.AudioPositionNoOffset() - TheOptions.AudioOffset
 }
 
+// Position that notes of given player are judged at.
+//
+// Human key presses are judged with input offset.
+func (gs *GameScreen) judgePosition(player FnfPlayerNo) time.Duration {
+	if isPlayerHuman(player, gs.IsBotPlay(), gs.OpponentMode) {
+		return gs.AudioPosition() - TheOptions.InputOffset
+	}
+	return gs.AudioPosition()
+}
+
 func (gs *GameScreen) SetAudioPositionNoOffset(at time.Duration) {
 	if !gs.IsSongLoaded {
 		ErrorLogger.Printf("GameScreen: Called when song is not loaded")
@@ -465,13 +967,14 @@ func (gs *GameScreen) SetAudioPositionNoOffset(at time.Duration) {
 	}
 
 	gs.audioPosition = at
-	gs.prevPlayerPosition = at
+	gs.audioClock.Reset(at, gs.AudioSpeed())
+	gs.resetVoiceDrift()
 
 	if gs.InstPlayer.IsReady() {
 		gs.InstPlayer.SetPosition(at)
 	}
-	if gs.VoicePlayer.IsReady() {
-		gs.VoicePlayer.SetPosition(at)
+	for _, player := range gs.followingPlayers() {
+		player.SetPosition(at)
 	}
 }
 
@@ -493,8 +996,8 @@ func (gs *GameScreen) AudioDuration() time.Duration {
 		return gs.InstPlayer.AudioDuration()
 	}
 
-	if gs.VoicePlayer.IsReady() {
-		return gs.VoicePlayer.AudioDuration()
+	if len(gs.VoicePlayers) > 0 {
+		return gs.VoicePlayers[0].AudioDuration()
 	}
 
 	ErrorLogger.Printf("GameScreen: Failed to get audio duration")
@@ -508,19 +1011,25 @@ func (gs *GameScreen) AudioDecodedDuration() time.Duration {
 		return 0
 	}
 
-	if gs.InstPlayer.IsReady() && gs.VoicePlayer.IsReady() {
-		return min(gs.InstPlayer.DecodedDuration(), gs.VoicePlayer.DecodedDuration())
-	} else {
-		if gs.InstPlayer.IsReady() {
-			return gs.InstPlayer.DecodedDuration()
-		} else if gs.VoicePlayer.IsReady() {
-			return gs.VoicePlayer.DecodedDuration()
-		}
+	var players []*VaryingSpeedPlayer
+
+	if gs.InstPlayer.IsReady() {
+		players = append(players, gs.InstPlayer)
 	}
+	players = append(players, gs.VoicePlayers...)
 
-	ErrorLogger.Printf("GameScreen: Failed to get decoded audio duration")
+	if len(players) <= 0 {
+		ErrorLogger.Printf("GameScreen: Failed to get decoded audio duration")
+		return 0
+	}
 
-	return 0
+	decoded := players[0].DecodedDuration()
+
+	for _, player := range players[1:] {
+		decoded = min(decoded, player.DecodedDuration())
+	}
+
+	return decoded
 }
 
 func (gs *GameScreen) AudioSpeed() float64 {
@@ -541,8 +1050,8 @@ func (gs *GameScreen) SetAudioSpeed(speed float64) {
 	if gs.InstPlayer.IsReady() {
 		gs.InstPlayer.SetSpeed(speed)
 	}
-	if gs.VoicePlayer.IsReady() {
-		gs.VoicePlayer.SetSpeed(speed)
+	for _, player := range gs.followingPlayers() {
+		player.SetSpeed(speed)
 	}
 
 	gs.AudioSpeedSetAt = GlobalTimerNow()
@@ -578,6 +1087,10 @@ func (gs *GameScreen) TempPause(howLong time.Duration) {
 }
 
 func (gs *GameScreen) OnlyTemporarilyPaused() bool {
+	if gs.isCountingIn {
+		return true
+	}
+
 	return gs.tempPauseFrameCounter > 0 &&
 		gs.wasPlayingWhenTempPause && !gs.IsPlayingAudio()
 }
@@ -585,6 +1098,9 @@ func (gs *GameScreen) OnlyTemporarilyPaused() bool {
 func (gs *GameScreen) ClearTempPause() {
 	gs.wasPlayingWhenTempPause = false
 	gs.tempPauseFrameCounter = -10
+
+	gs.isCountingIn = false
+	gs.countInAfterTempPause = false
 }
 
 func (gs *GameScreen) ClearRewind() {
@@ -608,6 +1124,7 @@ func (gs *GameScreen) resetGameStatesImpl(preservePastState bool) {
 	}
 
 	gs.PopupQueue.Clear()
+	gs.HitErrorBar.Clear()
 
 	gs.Pstates = [FnfPlayerSize]PlayerState{}
 
@@ -622,6 +1139,17 @@ func (gs *GameScreen) resetGameStatesImpl(preservePastState bool) {
 		}
 	}
 
+	gs.Heatmap.MarkDirty()
+
+	if gs.IsReplaying() {
+		// step notes that we just reset again
+		gs.ReplayPlayer.Seek(gs.AudioPosition() - HitWindow()/2)
+	} else if preservePastState {
+		gs.truncateReplayEvents(gs.AudioPosition() - HitWindow()/2)
+	} else {
+		gs.replayEvents = gs.replayEvents[:0]
+	}
+
 	if preservePastState {
 		var newMispresses []Mispress
 
@@ -700,19 +1228,12 @@ func (gs *GameScreen) CountEvents(player FnfPlayerNo) (int, [HitRatingSize]int)
 	return misses, hits
 }
 
-func (gs *GameScreen) PlayHitSound() {
-	if TheOptions.HitSoundVolume < 0.001 { // just in case
-		return
-	}
-
-	gs.hitSoundPlayers[gs.hitSoundPlayerIndex].Rewind()
-	gs.hitSoundPlayers[gs.hitSoundPlayerIndex].Play()
-
-	gs.hitSoundPlayerIndex++
+func (gs *GameScreen) PlayHitSound(dir NoteDir, rating FnfHitRating) {
+	PlaySoundEffect(HitSoundEffect(dir, rating))
+}
 
-	if gs.hitSoundPlayerIndex >= len(gs.hitSoundPlayers) {
-		gs.hitSoundPlayerIndex = 0
-	}
+func (gs *GameScreen) PlayMissSound() {
+	PlaySoundEffect(SoundEffectMiss)
 }
 
 func (gs *GameScreen) Update(deltaTime time.Duration) {
@@ -721,6 +1242,22 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 		return
 	}
 
+	// check if background decoding failed
+	if !gs.reportedDecodingError {
+		err := gs.InstPlayer.DecodingError()
+		for _, player := range gs.VoicePlayers {
+			if err == nil {
+				err = player.DecodingError()
+			}
+		}
+
+		if err != nil {
+			ErrorLogger.Printf("failed to decode song audio: %v", err)
+			DisplayAlert("failed to decode song audio, rest of the song will be silent")
+			gs.reportedDecodingError = true
+		}
+	}
+
 	// print bpm to debug
 	{
 		bpm := gs.Song.GetBpmAt(gs.AudioPosition())
@@ -759,7 +1296,14 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 	// stopping after we finished playing audio
 	// =============================================
 	if gs.AudioPosition() > GSC.PadEnd+gs.AudioDurationUnpadded()+GSC.StopAfter {
+		wasPlaying := gs.IsPlayingAudio()
+
 		gs.PauseAudio()
+
+		// show result if song ended while we were playing it
+		if wasPlaying && !gs.IsBotPlay() && !gs.DrawMenu {
+			gs.ShowResult()
+		}
 	}
 
 	// =============================================
@@ -768,11 +1312,21 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 	gs.tempPauseFrameCounter -= 1
 	if gs.tempPauseFrameCounter <= 0 {
 		if gs.wasPlayingWhenTempPause {
-			gs.PlayAudio()
+			if gs.countInAfterTempPause {
+				gs.PlayAudioWithCountIn()
+			} else {
+				gs.PlayAudio()
+			}
 			gs.wasPlayingWhenTempPause = false
 		}
+		gs.countInAfterTempPause = false
 	}
 
+	// =============================================
+	// count-in
+	// =============================================
+	gs.updateCountIn()
+
 	// =============================================
 	// menu stuff
 	// =============================================
@@ -789,8 +1343,48 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 
 			gs.Menu.SetItemBValue(gs.RewindOnMistakeMenuItemId, false, gs.RewindOnMistake)
 
+			gs.Menu.SetItemBValue(gs.SpeedTrainerMenuItemId, false, gs.SpeedTrainer.Enabled)
+
+			// update trouble spots
+			{
+				gs.TroubleSpots = FindTroubleSpots(gs.TroubleEvents)
+
+				hasSpots := len(gs.TroubleSpots) > 0
+
+				gs.Menu.SetItemHidden(gs.TroubleSpotsMenuItemId, !hasSpots)
+				gs.Menu.SetItemHidden(gs.TroubleSpotJumpMenuItemId, !hasSpots)
+				gs.Menu.SetItemHidden(gs.TroubleSpotLoopMenuItemId, !hasSpots)
+
+				if hasSpots {
+					var spotList []string
+					for _, spot := range gs.TroubleSpots {
+						spotList = append(spotList, troubleSpotStr(spot))
+					}
+
+					gs.troubleSpotIndex = 0
+					gs.Menu.SetItemList(gs.TroubleSpotsMenuItemId, spotList, 0)
+				}
+			}
+
 			gs.Menu.SetItemBValue(gs.OpponentModeMenuItemId, false, gs.OpponentMode)
 
+			for track := MixerTrack(0); track < MixerTrackSize; track++ {
+				gs.Menu.SetItemNvalue(gs.MixerVolumeMenuItemIds[track], false, f32(TheOptions.Mixer[track].Volume)*10)
+				gs.Menu.SetItemBValue(gs.MixerMuteMenuItemIds[track], false, TheOptions.Mixer[track].Mute)
+			}
+
+			for i, id := range gs.VoiceMuteMenuItemIds {
+				gs.Menu.SetItemBValue(id, false, gs.voiceMuted[i])
+			}
+
+			gs.Menu.SetItemBValue(gs.MuteOwnVoiceMenuItemId, false, gs.muteOwnVoice)
+			gs.Menu.SetItemHidden(gs.MuteOwnVoiceMenuItemId, !gs.hasVoiceStemsPerPlayer())
+
+			gs.Menu.SetItemHidden(gs.StopReplayMenuItemId, !gs.IsReplaying())
+
+			_, hasOverride := GetSongOverride(gs.PathGroup, gs.SelectedDifficulty)
+			gs.Menu.SetItemHidden(gs.ClearSongOverrideMenuItemId, !hasOverride)
+
 			var difficultyList []string
 			var difficultySelected int
 
@@ -821,6 +1415,25 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 
 	gs.Menu.Update(deltaTime)
 
+	// =============================================
+	// bookmark name input
+	// =============================================
+	if gs.bookMarkNameInput.IsOpen() {
+		DisableInput(gs.InputId)
+		EnableInput(gs.bookMarkNameInput.InputId)
+
+		gs.TempPause(time.Millisecond * 5)
+
+		done, canceled := gs.bookMarkNameInput.Update(deltaTime)
+
+		if done && !canceled && gs.BookMarkSet {
+			gs.BookMarks[gs.BookMarkIndex].Name = gs.bookMarkNameInput.Text
+			gs.saveBookMarks()
+		}
+	} else {
+		DisableInput(gs.bookMarkNameInput.InputId)
+	}
+
 	if gs.DrawMenu {
 		if botPlay, ok := gs.Menu.GetItemBValue(gs.BotPlayMenuItemId); ok {
 			if botPlay != gs.IsBotPlay() {
@@ -833,8 +1446,16 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 				difficulty := FnfDifficulty(d)
 				if dStr == str {
 					if difficulty != gs.SelectedDifficulty {
+						// replay is for a specific chart
+						if gs.IsReplaying() {
+							gs.StopReplay()
+						}
 						gs.SelectedDifficulty = difficulty
 						gs.SetSong(gs.Songs[gs.SelectedDifficulty])
+						gs.reloadMetronome()
+						gs.loadBookMarks()
+						gs.loadGhost()
+						gs.applySongOverride()
 					}
 				}
 			}
@@ -901,6 +1522,10 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 		if gs.rewindT > 1 {
 			gs.rewindQueue.Dequeue()
 			gs.rewindStarted = false
+
+			if gs.rewindQueue.IsEmpty() {
+				gs.countInAfterTempPause = true
+			}
 		}
 
 		positionArbitraryChange = true
@@ -910,15 +1535,25 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 	// handle user input
 	// =============================================
 	{
+		// review mode
+		if AreKeysPressed(gs.InputId, TheKM[ReviewModeKey]) {
+			gs.SetReviewMode(!gs.IsReviewing)
+		}
+
 		// pause unpause
 		if AreKeysPressed(gs.InputId, TheKM[PauseKey]) {
+			// user wants to play, so leave review mode
+			if gs.IsReviewing {
+				gs.SetReviewMode(false)
+			}
+
 			if gs.IsPlayingAudio() {
 				gs.PauseAudio()
 			} else {
 				if gs.OnlyTemporarilyPaused() {
 					gs.ClearTempPause()
 				} else {
-					gs.PlayAudio()
+					gs.PlayAudioWithCountIn()
 				}
 			}
 			gs.ClearRewind()
@@ -926,11 +1561,37 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 
 		// book marking
 		if AreKeysPressed(gs.InputId, TheKM[SetBookMarkKey]) {
-			gs.BookMarkSet = !gs.BookMarkSet
+			gs.ToggleBookMarkAt(gs.AudioPosition())
+			gs.ClearRewind()
+		}
+
+		if len(gs.BookMarks) > 0 {
+			bookMarkIndex := gs.BookMarkIndex
+			changedBookMark := false
+
+			if AreKeysPressed(gs.InputId, TheKM[PrevBookMarkKey]) {
+				bookMarkIndex--
+				changedBookMark = true
+			}
+			if AreKeysPressed(gs.InputId, TheKM[NextBookMarkKey]) {
+				bookMarkIndex++
+				changedBookMark = true
+			}
+
+			if changedBookMark {
+				gs.SelectBookMark(bookMarkIndex)
+
+				positionArbitraryChange = true
+				gs.SetAudioPosition(gs.BookMark)
+				gs.ClearRewind()
+			}
+		}
+
+		if AreKeysPressed(gs.InputId, TheKM[RenameBookMarkKey]) {
 			if gs.BookMarkSet {
-				gs.BookMark = gs.AudioPosition()
+				gs.bookMarkNameInput.Open(
+					"Bookmark Name", gs.BookMarks[gs.BookMarkIndex].Name)
 			}
-			gs.ClearRewind()
 		}
 
 		// speed change
@@ -1037,6 +1698,29 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 			}
 		}
 
+		// loop
+		if AreKeysPressed(gs.InputId, TheKM[LoopStartKey]) {
+			gs.SetLoopStart(gs.AudioPosition())
+		}
+		if AreKeysPressed(gs.InputId, TheKM[LoopEndKey]) {
+			gs.SetLoopEnd(gs.AudioPosition())
+		}
+		if AreKeysPressed(gs.InputId, TheKM[ClearLoopKey]) {
+			gs.ClearLoop()
+		}
+
+		// cycle through trouble spots, most severe first
+		if AreKeysPressed(gs.InputId, TheKM[NextTroubleSpotKey]) {
+			gs.TroubleSpots = FindTroubleSpots(gs.TroubleEvents)
+
+			if len(gs.TroubleSpots) > 0 {
+				gs.troubleSpotKeyIndex = (gs.troubleSpotKeyIndex + 1) % len(gs.TroubleSpots)
+
+				positionArbitraryChange = true
+				gs.JumpToTroubleSpot(gs.troubleSpotKeyIndex)
+			}
+		}
+
 		// handle progress bar
 		//
 		// NOTE : I think handling progress bar last is important
@@ -1049,15 +1733,42 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 			gs.isProgressBarInFocus = true
 		}
 
-		if IsMouseButtonUp(gs.InputId, rl.MouseButtonLeft) || !IsInputEnabled(gs.InputId) {
-			gs.isProgressBarInFocus = false
-		}
+		if IsMouseButtonUp(gs.InputId, rl.MouseButtonLeft) || !IsInputEnabled(gs.InputId) {
+			gs.isProgressBarInFocus = false
+		}
+
+		if gs.isProgressBarInFocus {
+			gs.ClearRewind()
+			gs.TempPause(time.Millisecond * 60)
+			positionArbitraryChange = true
+			gs.SetAudioPositionNoOffset(gs.ProgressBarCursorTime())
+		}
+
+		// set loop by dragging progress bar with right mouse button
+		if gs.ProgressBarHovering() &&
+			IsMouseButtonPressed(gs.InputId, rl.MouseButtonRight) {
+			gs.isLoopDragging = true
+			gs.loopDragFrom = gs.ProgressBarCursorTime() - TheOptions.AudioOffset
+		}
+
+		if gs.isLoopDragging {
+			dragTo := gs.ProgressBarCursorTime() - TheOptions.AudioOffset
+
+			gs.LoopStart = min(gs.loopDragFrom, dragTo)
+			gs.LoopEnd = max(gs.loopDragFrom, dragTo)
+			gs.LoopStartSet = true
+			gs.LoopEndSet = true
+
+			gs.resetLoopCounter()
+
+			if IsMouseButtonUp(gs.InputId, rl.MouseButtonRight) || !IsInputEnabled(gs.InputId) {
+				gs.isLoopDragging = false
 
-		if gs.isProgressBarInFocus {
-			gs.ClearRewind()
-			gs.TempPause(time.Millisecond * 60)
-			positionArbitraryChange = true
-			gs.SetAudioPositionNoOffset(gs.ProgressBarCursorTime())
+				// user probably just clicked
+				if gs.LoopEnd-gs.LoopStart < time.Millisecond*100 {
+					gs.ClearLoop()
+				}
+			}
 		}
 
 		// handle changing audio offset
@@ -1092,6 +1803,26 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 	// end of handling user input
 	// =============================================
 
+	// song never plays in review mode
+	if gs.IsReviewing && gs.IsPlayingAudio() {
+		gs.PauseAudio()
+	}
+
+	// =============================================
+	// go back to loop start
+	// =============================================
+	if gs.IsLoopActive() && gs.IsPlayingAudio() && !gs.isLoopDragging {
+		if gs.AudioPosition() >= gs.LoopEnd {
+			gs.recordLoopPass()
+
+			gs.ClearRewind()
+			positionArbitraryChange = true
+			gs.SetAudioPosition(gs.LoopRestartPosition())
+
+			gs.PlayAudioWithCountIn()
+		}
+	}
+
 	if positionArbitraryChange {
 		if !gs.IsPlayingAudio() {
 			gs.positionChangedWhilePaused = true
@@ -1111,38 +1842,39 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 
 	prevAudioPos := gs.audioPosition
 
-	// currently audio player position's delta is 0 or 10ms
-	// so we are trying to calculate better audio position
+	// audio player position moves in steps the size of audio buffer
+	// so we use smoothed clock while playing
 	if !positionArbitraryChange {
 		currentPlayerPos := gs.InstPlayer.Position()
 
-		if !positionArbitraryChange {
-			if !gs.IsPlayingAudio() {
-				gs.audioPosition = currentPlayerPos
-			} else {
-				delta := time.Duration((float64(deltaTime) * gs.AudioSpeed()))
-				if delta > 0 { // just in case...
-					if gs.audioPosition < currentPlayerPos {
-						gs.audioPosition += delta
-						for gs.audioPosition < gs.prevPlayerPosition {
-							gs.audioPosition += delta
-						}
-					}
-				}
-
-				if gs.prevPlayerPosition < currentPlayerPos {
-					gs.prevPlayerPosition = currentPlayerPos
-				}
-			}
+		if !gs.IsPlayingAudio() {
+			gs.audioPosition = currentPlayerPos
+			gs.audioClock.Reset(currentPlayerPos, gs.AudioSpeed())
+			gs.resetVoiceDrift()
+		} else {
+			gs.audioPosition = gs.audioClock.Update(currentPlayerPos, gs.AudioSpeed())
+			gs.correctVoiceDrift(currentPlayerPos)
 		}
 	}
 
+	if PrintDebugMsg {
+		DebugPrint("Clock Correction", fmt.Sprintf("%v", gs.audioClock.Correction))
+		DebugPrint("Voice Drift Correction", fmt.Sprintf("%v", gs.voiceDriftCorrection))
+	}
+
+	gs.Metronome.Update()
+	gs.updateVoiceMutes()
+
 	prevAudioPos -= TheOptions.AudioOffset
 	audioPos := gs.AudioPosition()
 
+	// human key presses are judged with input offset
+	prevInputPos := prevAudioPos - TheOptions.InputOffset
+	inputPos := audioPos - TheOptions.InputOffset
+
 	wasKeyPressed := gs.isKeyPressed
 
-	if !gs.IsBotPlay() {
+	if !gs.IsBotPlay() && !gs.IsReplaying() {
 		for dir, keys := range NoteKeysArr() {
 			if AreKeysDown(gs.InputId, keys...) {
 				gs.isKeyPressed[gs.mainPlayer()][dir] = true
@@ -1171,10 +1903,45 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 		}
 	}
 
+	// record key presses and releases
+	if !gs.IsBotPlay() && !gs.IsReplaying() && gs.IsPlayingAudio() {
+		for dir := range NoteDirSize {
+			if wasKeyPressed[gs.mainPlayer()][dir] != gs.isKeyPressed[gs.mainPlayer()][dir] {
+				gs.replayEvents = append(gs.replayEvents, ReplayEvent{
+					Time:      inputPos - GSC.PadStart,
+					PrevTime:  prevInputPos - GSC.PadStart,
+					Direction: dir,
+					Pressed:   gs.isKeyPressed[gs.mainPlayer()][dir],
+				})
+			}
+		}
+	}
+
 	var noteEvents []NoteEvent
 
+	// mispresses of the replay
+	var replayMispresses []Mispress
+
 	for player := FnfPlayerNo(0); player < FnfPlayerSize; player++ {
-		if isPlayerHuman(player, gs.IsBotPlay(), gs.OpponentMode) {
+		if gs.IsReplaying() && player == gs.mainPlayer() {
+			var eventsReplay []NoteEvent
+
+			// replay events were recorded at input position
+			gs.Pstates[player], eventsReplay, replayMispresses = gs.ReplayPlayer.Update(
+				gs.Song,
+				gs.Pstates[player],
+				player,
+				inputPos,
+				gs.AudioStopAt(),
+				gs.IsPlayingAudio(),
+				HitWindow(),
+				gs.noteIndexStart,
+			)
+
+			gs.isKeyPressed[player] = gs.ReplayPlayer.Keys()
+
+			noteEvents = append(noteEvents, eventsReplay...)
+		} else if isPlayerHuman(player, gs.IsBotPlay(), gs.OpponentMode) {
 			var eventsHuman []NoteEvent
 
 			gs.Pstates[player], eventsHuman = UpdateNotesAndStatesForHuman(
@@ -1183,8 +1950,8 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 				player,
 				wasKeyPressed[player],
 				gs.isKeyPressed[player],
-				prevAudioPos,
-				audioPos,
+				prevInputPos,
+				inputPos,
 				gs.AudioStopAt(),
 				gs.IsPlayingAudio(),
 				HitWindow(),
@@ -1226,9 +1993,11 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 		}
 	}
 
+	// NOTE : input position is always behind audio position
+	// so notes human is still judging aren't skipped
 	gs.noteIndexStart = CalculateNewNoteIndexStart(
 		gs.Song,
-		audioPos,
+		inputPos,
 		HitWindow(),
 		gs.noteIndexStart,
 	)
@@ -1279,6 +2048,10 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 					DiffStr: diffStr,
 				}
 				gs.PopupQueue.Enqueue(popup)
+
+				// NOTE : hit error bar uses diff in audio time
+				// so that ticks land inside the hit window band of their rating
+				gs.HitErrorBar.Push(diff)
 			}
 		}
 
@@ -1289,7 +2062,18 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 
 			note := gs.Song.Notes[e.Index]
 			if e.IsFirstHit() && note.Player == gs.mainPlayer() {
-				gs.PlayHitSound()
+				gs.PlayHitSound(note.Direction, GetHitRating(note.StartsAt, e.Time))
+			}
+		}
+
+		playMissSoundIfHumanPlayerMissed := func(e NoteEvent) {
+			if gs.IsBotPlay() {
+				return
+			}
+
+			note := gs.Song.Notes[e.Index]
+			if e.IsMiss() && note.Player == gs.mainPlayer() {
+				gs.PlayMissSound()
 			}
 		}
 
@@ -1318,10 +2102,33 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 			}
 		}
 
+		recordTrouble := func(e NoteEvent) {
+			if gs.IsBotPlay() || gs.IsReplaying() {
+				return
+			}
+
+			note := gs.Song.Notes[e.Index]
+			if note.Player != gs.mainPlayer() {
+				return
+			}
+
+			if e.IsFirstHit() {
+				if GetHitRating(note.StartsAt, e.Time) == HitRatingBad {
+					gs.TroubleEvents = append(gs.TroubleEvents, TroubleEvent{
+						Kind: TroubleBadHit, Time: note.StartsAt,
+					})
+				}
+			} else if e.IsMiss() {
+				gs.TroubleEvents = append(gs.TroubleEvents, TroubleEvent{
+					Kind: TroubleMiss, Time: note.StartsAt,
+				})
+			}
+		}
+
 		queuedRewind := false
 
 		queueRewinds := func(player FnfPlayerNo, direction NoteDir, rewinds ...AnimatedRewind) {
-			if queuedRewind {
+			if queuedRewind || gs.IsReplaying() {
 				return
 			}
 
@@ -1341,8 +2148,15 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 		// ===================
 		// handle mispresses
 		// ===================
+		// replay checks mispresses by itself
+		gs.Mispresses = append(gs.Mispresses, replayMispresses...)
+
 		if !TheOptions.GhostTapping {
 			for player := FnfPlayerNo(0); player < FnfPlayerSize; player++ {
+				if gs.IsReplaying() && player == gs.mainPlayer() {
+					continue
+				}
+
 				for dir := NoteDir(0); dir < NoteDirSize; dir++ {
 					mispressed := (gs.Pstates[player].IsHoldingBadKey[dir] &&
 						gs.Pstates[player].IsKeyJustPressed[dir])
@@ -1379,6 +2193,13 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 						gs.Mispresses = append(gs.Mispresses, Mispress{
 							Player: player, Direction: dir, Time: gs.AudioPosition(),
 						})
+
+						if player == gs.mainPlayer() && !gs.IsBotPlay() {
+							gs.TroubleEvents = append(gs.TroubleEvents, TroubleEvent{
+								Kind: TroubleMispress, Time: gs.AudioPosition(),
+							})
+							gs.PlayMissSound()
+						}
 					}
 				}
 			}
@@ -1436,11 +2257,14 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 			events := gs.NoteEvents[e.Index]
 
 			if len(events) <= 0 {
+				recordTrouble(e)
 				logNoteEvent(e)
 				pushPopupIfHumanPlayerHit(e)
 				playHitSoundIfHumanPlayerHit(e)
+				playMissSoundIfHumanPlayerMissed(e)
 				pushNoteSplashIfMainPlayerSickHit(e)
 				gs.NoteEvents[e.Index] = append(events, e)
+				gs.Heatmap.MarkDirty()
 			} else {
 				if e.IsMiss() {
 					// try to find last miss
@@ -1454,8 +2278,11 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 					}
 
 					if lastMiss.IsNone() {
+						recordTrouble(e)
 						logNoteEvent(e)
+						playMissSoundIfHumanPlayerMissed(e)
 						gs.NoteEvents[e.Index] = append(events, e)
+						gs.Heatmap.MarkDirty()
 					} else {
 						// if there are any previous misses
 						// only report miss after every step
@@ -1473,17 +2300,20 @@ func (gs *GameScreen) Update(deltaTime time.Duration) {
 						if stepCount > lastStepCount {
 							logNoteEvent(e)
 							gs.NoteEvents[e.Index] = append(events, e)
+							gs.Heatmap.MarkDirty()
 						}
 					}
 				} else {
 					last := events[len(events)-1]
 
 					if !last.SameKind(e) {
+						recordTrouble(e)
 						logNoteEvent(e)
 						pushPopupIfHumanPlayerHit(e)
 						playHitSoundIfHumanPlayerHit(e)
 						pushNoteSplashIfMainPlayerSickHit(e)
 						gs.NoteEvents[e.Index] = append(events, e)
+						gs.Heatmap.MarkDirty()
 					}
 				}
 			}
@@ -1965,6 +2795,16 @@ func (gs *GameScreen) Draw() {
 	// ============================================
 	if gs.IsBotPlay() {
 		gs.DrawBotPlayIcon()
+	} else if gs.IsReplaying() {
+		gs.DrawReplayIcon()
+	}
+
+	if gs.IsReviewing {
+		gs.DrawReviewIcon()
+	}
+
+	if gs.isCountingIn && !gs.DrawMenu {
+		gs.DrawCountIn()
 	}
 
 	// ============================================
@@ -2026,6 +2866,13 @@ func (gs *GameScreen) Draw() {
 		}
 	}
 
+	// ============================================
+	// draw ghost
+	// ============================================
+	if gs.Ghost != nil && gs.Ghost.Player == gs.mainPlayer() {
+		gs.DrawGhostMarkers()
+	}
+
 	// ============================================
 	// draw notes
 	// ============================================
@@ -2112,7 +2959,7 @@ func (gs *GameScreen) Draw() {
 				arrowStroke := noteStroke[note.Direction]
 
 				// if we are not holding note and it passed the hit window, grey it out
-				if !isHoldingNote && note.StartPassedWindow(gs.AudioPosition(), HitWindow()) && !gs.positionChangedWhilePaused {
+				if !isHoldingNote && note.StartPassedWindow(gs.judgePosition(note.Player), HitWindow()) && !gs.positionChangedWhilePaused {
 					arrowFill = noteFillGrey[note.Direction]
 					arrowStroke = noteStrokeGrey[note.Direction]
 				}
@@ -2122,6 +2969,10 @@ func (gs *GameScreen) Draw() {
 					arrowStroke = noteStrokeMistake[note.Direction]
 				}
 
+				if drawEvent && gs.IsReviewing {
+					arrowFill = reviewTint(arrowFill, note, noteEvents)
+				}
+
 				if TheOptions.MiddleScroll && note.Player == gs.otherPlayer() {
 					arrowFill = fadeC(arrowFill, GSC.MiddleScrollFade)
 					arrowStroke = fadeC(arrowFill, GSC.MiddleScrollFade)
@@ -2137,7 +2988,7 @@ func (gs *GameScreen) Draw() {
 			arrowFill := noteFill[note.Direction]
 			arrowStroke := noteStroke[note.Direction]
 
-			if note.StartPassedWindow(gs.AudioPosition(), HitWindow()) && !gs.positionChangedWhilePaused {
+			if note.StartPassedWindow(gs.judgePosition(note.Player), HitWindow()) && !gs.positionChangedWhilePaused {
 				arrowFill = noteFillGrey[note.Direction]
 				arrowStroke = noteStrokeGrey[note.Direction]
 			}
@@ -2147,6 +2998,10 @@ func (gs *GameScreen) Draw() {
 				arrowStroke = noteStrokeMistake[note.Direction]
 			}
 
+			if drawEvent && gs.IsReviewing {
+				arrowFill = reviewTint(arrowFill, note, noteEvents)
+			}
+
 			if TheOptions.MiddleScroll && note.Player == gs.otherPlayer() {
 				arrowFill = fadeC(arrowFill, GSC.MiddleScrollFade)
 				arrowStroke = fadeC(arrowFill, GSC.MiddleScrollFade)
@@ -2156,6 +3011,13 @@ func (gs *GameScreen) Draw() {
 		}
 	}
 
+	// ============================================
+	// draw review info
+	// ============================================
+	if gs.IsReviewing {
+		gs.DrawReviewInfo()
+	}
+
 	// ============================================
 	// draw note splash
 	// ============================================
@@ -2386,11 +3248,35 @@ func (gs *GameScreen) Draw() {
 		}
 	}
 
+	// ============================================
+	// draw hit error bar
+	// ============================================
+	if TheOptions.HitErrorBar {
+		// draw it on the opposite side of the note receptors
+		barY := float32(SCREEN_HEIGHT - 50)
+		if TheOptions.DownScroll {
+			barY = 30
+		}
+		gs.HitErrorBar.Draw(SCREEN_WIDTH/2, barY)
+	}
+
+	// ============================================
+	// draw accuracy compared to ghost
+	// ============================================
+	if gs.Ghost != nil && gs.Ghost.Player == gs.mainPlayer() {
+		gs.DrawGhostDelta()
+	}
+
 	// ============================================
 	// draw progress bar
 	// ============================================
 	gs.DrawProgressBar()
 
+	// ============================================
+	// draw loop info
+	// ============================================
+	gs.DrawLoopInfo()
+
 	// ============================================
 	// draw fading text
 	// ============================================
@@ -2413,6 +3299,11 @@ func (gs *GameScreen) Draw() {
 		rl.DrawRectangle(0, 0, SCREEN_WIDTH, SCREEN_HEIGHT, ToRlColor(FnfColor{0, 0, 0, 100}))
 		gs.Menu.Draw()
 	}
+
+	// ============================================
+	// draw bookmark name input
+	// ============================================
+	gs.bookMarkNameInput.Draw()
 }
 
 func (gs *GameScreen) NoteX(player FnfPlayerNo, dir NoteDir) float32 {
@@ -2631,8 +3522,8 @@ func DrawNoteArrow(x, y float32, arrowHeight float32, dir NoteDir, fill, stroke
 }
 
 func (gs *GameScreen) DrawBigBookMark() {
-	if gs.BookMarkSet {
-		relativeTime := gs.BookMark - gs.AudioPosition()
+	for i, bookMark := range gs.BookMarks {
+		relativeTime := bookMark.Time - gs.AudioPosition()
 
 		var bookMarkY float32
 
@@ -2665,29 +3556,52 @@ func (gs *GameScreen) DrawBigBookMark() {
 			X: 0, Y: 0, Width: SCREEN_WIDTH, Height: SCREEN_HEIGHT,
 		}
 
+		col := FnfColor{255, 255, 255, 255}
+		if i != gs.BookMarkIndex {
+			col.A = 120
+		}
+
 		if rl.CheckCollisionRecs(dstRect, screenRect) {
 			rl.DrawTexturePro(
 				BookMarkBigTex,
 				srcRect, dstRect,
-				rl.Vector2{}, 0, ToRlColor(FnfColor{255, 255, 255, 255}),
+				rl.Vector2{}, 0, ToRlColor(col),
 			)
+
+			if bookMark.Name != "" {
+				const fontSize = 30
+
+				textSize := MeasureText(SdfFontBold, bookMark.Name, fontSize, 0)
+
+				fill := FnfColor{255, 255, 255, col.A}
+				stroke := FnfColor{0, 0, 0, col.A}
+
+				DrawTextOutlined(
+					SdfFontBold, bookMark.Name,
+					rl.Vector2{dstRect.X + dstRect.Width + 10, bookMarkY - textSize.Y*0.5},
+					fontSize, 0, ToRlColor(fill), ToRlColor(stroke), 4,
+				)
+			}
 		}
 	}
 }
 
 func (gs *GameScreen) DrawBotPlayIcon() {
+	gs.drawStatusText("Bot Play", 190)
+}
+
+func (gs *GameScreen) drawStatusText(text string, y float32) {
 	const centerX = SCREEN_WIDTH / 2
 
 	const fontSize = 65
 
-	textSize := MeasureText(FontBold, "Bot Play", fontSize, 0)
+	textSize := MeasureText(FontBold, text, fontSize, 0)
 
 	textX := f32(centerX - textSize.X*0.5)
-	textY := f32(190)
 
 	DrawText(
-		FontBold, "Bot Play",
-		rl.Vector2{textX, textY},
+		FontBold, text,
+		rl.Vector2{textX, y},
 		fontSize, 0, ToRlColor(FnfColor{0, 0, 0, 255}))
 }
 
@@ -2919,15 +3833,220 @@ func (gs *GameScreen) DrawBpmDebugGrid() {
 	}
 }
 
+func (gs *GameScreen) SelectBookMark(index int) {
+	if len(gs.BookMarks) <= 0 {
+		gs.BookMarkIndex = 0
+		gs.BookMarkSet = false
+		return
+	}
+
+	// wrap around
+	index = index % len(gs.BookMarks)
+	if index < 0 {
+		index += len(gs.BookMarks)
+	}
+
+	gs.BookMarkIndex = index
+	gs.BookMark = gs.BookMarks[index].Time
+	gs.BookMarkSet = true
+}
+
+// Adds bookmark at given time.
+// If there is already a bookmark near it, removes that bookmark instead.
+func (gs *GameScreen) ToggleBookMarkAt(at time.Duration) {
+	if TheOptions.SnapBookMarks {
+		at = gs.SnapToMeasure(at)
+	}
+
+	tolerance := HitWindow() / 2
+
+	for i, bookMark := range gs.BookMarks {
+		if AbsI(bookMark.Time-at) <= tolerance {
+			gs.BookMarks = slices.Delete(gs.BookMarks, i, i+1)
+			gs.SelectBookMark(min(gs.BookMarkIndex, len(gs.BookMarks)-1))
+			gs.saveBookMarks()
+			return
+		}
+	}
+
+	gs.BookMarks = append(gs.BookMarks, SongBookMark{Time: at})
+
+	slices.SortFunc(gs.BookMarks, func(a, b SongBookMark) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+
+	for i, bookMark := range gs.BookMarks {
+		if bookMark.Time == at {
+			gs.SelectBookMark(i)
+			break
+		}
+	}
+
+	gs.saveBookMarks()
+}
+
+// Returns start and end of the measure that given time is in.
+func (gs *GameScreen) MeasureAt(at time.Duration) (time.Duration, time.Duration) {
+	pos := GSC.PadStart
+
+	for {
+		next := pos + BeatsToTime(4, songBpmAt(gs.Song, pos))
+
+		if next > at {
+			return pos, next
+		}
+
+		pos = next
+	}
+}
+
+// Returns start of the measure that is closest to given time.
+func (gs *GameScreen) SnapToMeasure(at time.Duration) time.Duration {
+	if at <= GSC.PadStart {
+		return GSC.PadStart
+	}
+
+	start, end := gs.MeasureAt(at)
+
+	if at-start < end-at {
+		return start
+	}
+	return end
+}
+
+func (gs *GameScreen) loadBookMarks() {
+	key := SongDataKey(gs.PathGroup, gs.SelectedDifficulty)
+
+	gs.BookMarks = gs.BookMarks[:0]
+
+	for _, bookMark := range GetBookMarks(key) {
+		bookMark.Time += GSC.PadStart
+		gs.BookMarks = append(gs.BookMarks, bookMark)
+	}
+
+	gs.SelectBookMark(0)
+}
+
+func (gs *GameScreen) saveBookMarks() {
+	key := SongDataKey(gs.PathGroup, gs.SelectedDifficulty)
+
+	var bookMarks []SongBookMark
+
+	for _, bookMark := range gs.BookMarks {
+		bookMark.Time -= GSC.PadStart
+		bookMarks = append(bookMarks, bookMark)
+	}
+
+	SetBookMarks(key, bookMarks)
+
+	if err := SaveBookMarks(); err != nil {
+		ErrorLogger.Printf("failed to save bookmarks: %v", err)
+		DisplayAlert("failed to save bookmarks")
+	}
+}
+
+func (gs *GameScreen) addPlayRecord(record PlayRecord) {
+	AddPlayRecord(SongDataKey(gs.PathGroup, gs.SelectedDifficulty), record)
+
+	if err := SavePlayHistory(); err != nil {
+		ErrorLogger.Printf("failed to save play history: %v", err)
+		DisplayAlert("failed to save play history")
+	}
+}
+
+func (gs *GameScreen) ShowResult() {
+	stats := CalculatePlayStats(
+		gs.Song, gs.NoteEvents, gs.mainPlayer(), 0, gs.AudioDuration())
+
+	// user didn't actually play anything
+	if stats.JudgedCount <= 0 {
+		return
+	}
+
+	kind := PlayRecordFull
+	for _, note := range gs.Song.Notes {
+		if note.Player == gs.mainPlayer() {
+			if firstHit, missed := judgeNote(gs.NoteEvents[note.Index]); firstHit.IsNone() && !missed {
+				kind = PlayRecordPartial
+				break
+			}
+		}
+	}
+
+	if !gs.IsReplaying() {
+		record := NewPlayRecord(kind, stats, gs.AudioSpeed())
+		record.ReplayFile = gs.saveReplay()
+
+		gs.addPlayRecord(record)
+
+		// we might have a new personal best
+		gs.loadGhost()
+	}
+
+	TheResultScreen.SetResult(
+		stats,
+		gs.Songs[gs.SelectedDifficulty].SongName,
+		gs.SelectedDifficulty,
+		gs.AudioSpeed(),
+		gs.AudioDuration(),
+	)
+
+	gs.isShowingResult = true
+
+	ShowTransition(BlackPixel, func() {
+		SetNextScreen(TheResultScreen)
+		HideTransition()
+	})
+}
+
+// Called by ResultScreen before it switches back to GameScreen.
+func (gs *GameScreen) ReturnFromResult(at time.Duration) {
+	gs.SetAudioPosition(at)
+	gs.positionChangedWhilePaused = true
+}
+
+// Called by ResultScreen when it's not going back to GameScreen.
+func (gs *GameScreen) LeaveResult() {
+	gs.isShowingResult = false
+	gs.QuitBackgroundDecoding()
+
+	if gs.IsReplaying() {
+		gs.StopReplay()
+	}
+
+	gs.restoreOptionsBeforeOverride()
+}
+
+func (gs *GameScreen) QuitBackgroundDecoding() {
+	if gs.InstPlayer.IsReady() {
+		gs.InstPlayer.QuitBackgroundDecoding()
+	}
+	for _, player := range gs.VoicePlayers {
+		player.QuitBackgroundDecoding()
+	}
+}
+
 func (gs *GameScreen) BeforeScreenTransition() {
-	gs.zoom = 1.0
+	// coming back from result screen, keep everything as it is
+	if gs.isShowingResult {
+		gs.isShowingResult = false
+
+		gs.DrawMenu = false
+		gs.ClearTempPause()
+		gs.ClearRewind()
+
+		return
+	}
+
+	// NOTE : zoom is set in LoadSongs since song override might change it
 
 	gs.botPlay = false
 
 	gs.DrawMenu = false
 	gs.Menu.SelectItemAt(0, false)
 
-	gs.prevPlayerPosition = 0
+	gs.audioClock.Reset(0, 1)
+	gs.resetVoiceDrift()
 
 	gs.ClearTempPause()
 
@@ -2935,7 +4054,13 @@ func (gs *GameScreen) BeforeScreenTransition() {
 
 	gs.HelpMessage.BeforeScreenTransition()
 
-	gs.BookMarkSet = false
+	// NOTE : bookmarks are loaded in LoadSongs, don't clear them
+	gs.bookMarkNameInput.Close()
+
+	gs.SpeedTrainer.Enabled = false
+	gs.ClearLoop()
+
+	gs.IsReviewing = false
 
 	gs.Menu.BeforeScreenTransition()
 
@@ -2947,8 +4072,9 @@ func (gs *GameScreen) BeforeScreenTransition() {
 
 	gs.positionChangedWhilePaused = false
 
-	for _, player := range gs.hitSoundPlayers {
-		player.SetVolume(TheOptions.HitSoundVolume)
+	gs.InstPlayer.SetPreservePitch(TheOptions.PreservePitch)
+	for _, player := range gs.voicePlayerPool {
+		player.SetPreservePitch(TheOptions.PreservePitch)
 	}
 }
 
@@ -2957,11 +4083,32 @@ func (gs *GameScreen) BeforeScreenEnd() {
 		gs.PauseAudio()
 	}
 
-	if gs.InstPlayer.IsReady() {
-		gs.InstPlayer.QuitBackgroundDecoding()
+	gs.flushLoopRecord()
+
+	// we might come back from result screen
+	// so don't stop decoding
+	// (ResultScreen will call LeaveResult if we don't)
+	if !gs.isShowingResult {
+		gs.QuitBackgroundDecoding()
+
+		// replay changes hit windows, so stop it before other screens see them
+		if gs.IsReplaying() {
+			gs.StopReplay()
+		}
+
+		// same goes for song override
+		gs.restoreOptionsBeforeOverride()
 	}
-	if gs.VoicePlayer.IsReady() {
-		gs.VoicePlayer.QuitBackgroundDecoding()
+
+	// don't save hit windows that replay is using
+	// and options that song override is using
+	options := TheOptions
+	if gs.IsReplaying() {
+		TheOptions.HitWindows = gs.hitWindowsBeforeReplay
+	}
+	if gs.isSongOverrideApplied {
+		TheOptions.AudioOffset = gs.audioOffsetBeforeOverride
+		TheOptions.DownScroll = gs.downScrollBeforeOverride
 	}
 
 	if err := SaveSettings(); err != nil {
@@ -2969,6 +4116,8 @@ func (gs *GameScreen) BeforeScreenEnd() {
 		DisplayAlert("failed to save settings")
 	}
 
+	TheOptions = options
+
 	FpsDisplayY = FpsDisplayYDefault
 }
 
@@ -3061,6 +4210,9 @@ func (hm *GameHelpMessage) InitTextImage() {
 
 	printKeyBinding(f1, "set bookmark", SetBookMarkKey)
 	printKeyBinding(f1, "jump to bookmark", JumpToBookMarkKey)
+	printLabelAndText(f1, "prev/next bookmark",
+		GetKeyName(TheKM[PrevBookMarkKey])+"/"+GetKeyName(TheKM[NextBookMarkKey]))
+	printKeyBinding(f1, "rename bookmark", RenameBookMarkKey)
 	f1.Print("\n")
 
 	printKeyBinding(f2, "note spacing up", ZoomInKey)
@@ -3071,6 +4223,15 @@ func (hm *GameHelpMessage) InitTextImage() {
 	printKeyBinding(f2, "audio offset down", AudioOffsetDownKey)
 	f2.Print("\n")
 
+	printKeyBinding(f2, "set loop start", LoopStartKey)
+	printKeyBinding(f2, "set loop end", LoopEndKey)
+	printKeyBinding(f2, "clear loop", ClearLoopKey)
+	f2.Print("\n")
+
+	printKeyBinding(f2, "next trouble spot", NextTroubleSpotKey)
+	printKeyBinding(f2, "review mode", ReviewModeKey)
+	f2.Print("\n")
+
 	elements1 := f1.Elements(TextAlignLeft, 0, 20)
 	elements2 := f2.Elements(TextAlignLeft, 0, 20)
 
@@ -3431,6 +4592,28 @@ func (gs *GameScreen) DrawProgressBar() {
 		rl.DrawRectangleRec(audioPosBar, ToRlColor(FnfColor{255, 255, 255, 255}))
 	}
 
+	// draw accuracy heatmap
+	if heatmap := gs.UpdatedHeatmap(); len(heatmap.Bounds) > 0 {
+		bounds := heatmap.Bounds
+
+		for i, accuracy := range heatmap.Accuracies {
+			if accuracy < 0 {
+				continue
+			}
+
+			startX := f32(bounds[i])/f32(gs.AudioDuration())*inRect.Width + inRect.X
+			endX := f32(bounds[i+1])/f32(gs.AudioDuration())*inRect.Width + inRect.X
+
+			startX = Clamp(startX, inRect.X, inRect.X+inRect.Width)
+			endX = Clamp(endX, inRect.X, inRect.X+inRect.Width)
+
+			rl.DrawRectangleRec(
+				rl.Rectangle{X: startX, Y: inRect.Y, Width: endX - startX, Height: inRect.Height},
+				ToRlColor(HeatmapColor(accuracy)),
+			)
+		}
+	}
+
 	timeStamp := gs.AudioPositionNoOffset()
 	if gs.ProgressBarHovering() || gs.isProgressBarInFocus {
 		timeStamp = gs.ProgressBarCursorTime()
@@ -3517,10 +4700,55 @@ func (gs *GameScreen) DrawProgressBar() {
 		}
 	}
 
-	// draw bookmark
-	if gs.BookMarkSet {
+	// draw loop
+	{
+		timeToX := func(t time.Duration) float32 {
+			return inRect.X + inRect.Width*Clamp(f32(t)/f32(gs.AudioDuration()), 0, 1)
+		}
+
+		loopCol := FnfColor{0xFF, 0xD7, 0x00, 0xFF}
+
+		if gs.IsLoopActive() {
+			startX := timeToX(gs.LoopStart)
+			endX := timeToX(gs.LoopEnd)
+
+			regionCol := loopCol
+			regionCol.A = 120
+
+			rl.DrawRectangleRec(
+				rl.Rectangle{
+					X: startX, Y: outRect.Y,
+					Width: endX - startX, Height: outRect.Height,
+				},
+				ToRlColor(regionCol),
+			)
+		}
+
+		const markerW = 3
+
+		drawMarker := func(t time.Duration) {
+			x := timeToX(t)
+			rl.DrawRectangleRec(
+				rl.Rectangle{
+					X: x - markerW*0.5, Y: outRect.Y,
+					Width: markerW, Height: outRect.Height,
+				},
+				ToRlColor(loopCol),
+			)
+		}
+
+		if gs.LoopStartSet {
+			drawMarker(gs.LoopStart)
+		}
+		if gs.LoopEndSet {
+			drawMarker(gs.LoopEnd)
+		}
+	}
+
+	// draw bookmarks
+	for i, bookMark := range gs.BookMarks {
 		// center, not top left corner
-		bookMarkX := inRect.X + inRect.Width*f32(gs.BookMark)/f32(gs.AudioDuration())
+		bookMarkX := inRect.X + inRect.Width*f32(bookMark.Time)/f32(gs.AudioDuration())
 		bookMarkY := inRect.Y + inRect.Height*0.5
 
 		srcRect := rl.Rectangle{
@@ -3535,10 +4763,15 @@ func (gs *GameScreen) DrawProgressBar() {
 		dstRect.X = bookMarkX - dstRect.Width*0.5
 		dstRect.Y = bookMarkY - dstRect.Height*0.5
 
+		col := FnfColor{255, 255, 255, 255}
+		if i != gs.BookMarkIndex {
+			col.A = 120
+		}
+
 		rl.DrawTexturePro(
 			BookMarkSmallTex,
 			srcRect, dstRect,
-			rl.Vector2{}, 0, ToRlColor(FnfColor{255, 255, 255, 255}),
+			rl.Vector2{}, 0, ToRlColor(col),
 		)
 	}
 
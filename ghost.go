@@ -0,0 +1,234 @@
+package fnf
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Judgements of a replay that user can compare their play against.
+type Ghost struct {
+	Player FnfPlayerNo
+
+	// same layout as GameScreen.NoteEvents
+	NoteEvents [][]NoteEvent
+
+	// running totals of AccuracyDelta for notes whose judgement can't change anymore
+	deltaScore      int
+	deltaGhostScore int
+	deltaCount      int
+	deltaNext       int // index of first note that isn't in running totals
+	deltaUntil      time.Duration
+}
+
+func NewGhost(song FnfSong, replay Replay) *Ghost {
+	g := new(Ghost)
+
+	g.Player = mainPlayer(replay.OpponentMode)
+	g.NoteEvents = SimulateReplay(song, replay)
+
+	return g
+}
+
+// Compares current play with the ghost over notes that both have judged
+// and that start before given time.
+//
+// Returns current accuracy - ghost accuracy (positive if user is ahead).
+// Returns false if there are no notes to compare.
+func (g *Ghost) AccuracyDelta(song FnfSong, noteEvents [][]NoteEvent, until time.Duration) (float64, bool) {
+	// user went back, notes we counted might be played again
+	if until < g.deltaUntil {
+		g.deltaScore, g.deltaGhostScore, g.deltaCount = 0, 0, 0
+		g.deltaNext = 0
+	}
+	g.deltaUntil = until
+
+	// notes are missed once they pass hit window at input position
+	judgedUntil := until - TheOptions.InputOffset - HitWindow()
+
+	for ; g.deltaNext < len(song.Notes); g.deltaNext++ {
+		if song.Notes[g.deltaNext].StartsAt >= judgedUntil {
+			break
+		}
+		if score, ghostScore, ok := g.compareNote(song, noteEvents, g.deltaNext); ok {
+			g.deltaScore += score
+			g.deltaGhostScore += ghostScore
+			g.deltaCount++
+		}
+	}
+
+	score, ghostScore, count := g.deltaScore, g.deltaGhostScore, g.deltaCount
+
+	// notes that are still in hit window
+	for i := g.deltaNext; i < len(song.Notes); i++ {
+		if song.Notes[i].StartsAt >= until {
+			break
+		}
+		if noteScore, noteGhostScore, ok := g.compareNote(song, noteEvents, i); ok {
+			score += noteScore
+			ghostScore += noteGhostScore
+			count++
+		}
+	}
+
+	if count <= 0 {
+		return 0, false
+	}
+
+	return f64(score-ghostScore) / f64(count*RatingScores[HitRatingSick]), true
+}
+
+// Returns score of user and ghost for the note at index i.
+// Returns false if it's not ghost's note or either of them haven't judged it yet.
+func (g *Ghost) compareNote(song FnfSong, noteEvents [][]NoteEvent, i int) (int, int, bool) {
+	note := song.Notes[i]
+
+	if note.Player != g.Player {
+		return 0, 0, false
+	}
+	if i >= len(noteEvents) || i >= len(g.NoteEvents) {
+		return 0, 0, false
+	}
+
+	scoreOf := func(firstHit NoteEvent) int {
+		if firstHit.IsNone() {
+			return 0
+		}
+		return RatingScores[GetHitRating(note.StartsAt, firstHit.Time)]
+	}
+
+	firstHit, missed := judgeNote(noteEvents[i])
+	if firstHit.IsNone() && !missed {
+		return 0, 0, false
+	}
+
+	ghostHit, ghostMissed := judgeNote(g.NoteEvents[i])
+	if ghostHit.IsNone() && !ghostMissed {
+		return 0, 0, false
+	}
+
+	return scoreOf(firstHit), scoreOf(ghostHit), true
+}
+
+// Loads personal best replay as a ghost.
+func (gs *GameScreen) loadGhost() {
+	gs.Ghost = nil
+
+	if !TheOptions.Ghost {
+		return
+	}
+
+	best, ok := GetPersonalBest(SongDataKey(gs.PathGroup, gs.SelectedDifficulty))
+	if !ok || best.ReplayFile == "" {
+		return
+	}
+
+	dir, err := ReplaysDir()
+	if err != nil {
+		ErrorLogger.Printf("failed to load ghost: %v", err)
+		return
+	}
+
+	replay, err := LoadReplay(filepath.Join(dir, best.ReplayFile))
+	if err != nil {
+		// user might have deleted it, not a big deal
+		ErrorLogger.Printf("failed to load ghost: %v", err)
+		return
+	}
+
+	if replay.ChartHash != gs.ChartHashes[gs.SelectedDifficulty] {
+		return
+	}
+
+	gs.Ghost = NewGhost(gs.Song, replay)
+}
+
+// Draws where personal best run hit (or missed) each note.
+func (gs *GameScreen) DrawGhostMarkers() {
+	const markerHeight = 8
+	const alpha = 150
+
+	// markers are drawn where ghost hit the note which is at most a hit window away from it
+	span := gs.PixelsToTime(SCREEN_HEIGHT+markerHeight) + HitWindow()
+	pos := gs.AudioPosition()
+
+	start, _ := slices.BinarySearchFunc(gs.Song.Notes, pos-span, func(note FnfNote, t time.Duration) int {
+		return cmp.Compare(note.StartsAt, t)
+	})
+
+	for _, note := range gs.Song.Notes[start:] {
+		if note.StartsAt > pos+span {
+			break
+		}
+		if note.Player != gs.Ghost.Player {
+			continue
+		}
+
+		firstHit, missed := judgeNote(gs.Ghost.NoteEvents[note.Index])
+
+		var at time.Duration
+		var color FnfColor
+
+		if !firstHit.IsNone() {
+			at = firstHit.Time
+			color = RatingColors[GetHitRating(note.StartsAt, firstHit.Time)]
+		} else if missed {
+			at = note.StartsAt
+			color = FnfColor{0xFF, 0x4D, 0x4D, 0xFF}
+		} else {
+			continue
+		}
+
+		y := gs.TimeToY(at)
+
+		if y < -markerHeight || y > SCREEN_HEIGHT+markerHeight {
+			continue
+		}
+
+		x := gs.NoteX(note.Player, note.Direction)
+		width := GSC.NotesSize * 0.8
+
+		color.A = alpha
+
+		rl.DrawRectangleRounded(
+			rl.Rectangle{X: x - width*0.5, Y: y - markerHeight*0.5, Width: width, Height: markerHeight},
+			1, 5, ToRlColor(color),
+		)
+	}
+}
+
+// Draws how far ahead or behind user is compared to personal best run.
+func (gs *GameScreen) DrawGhostDelta() {
+	delta, ok := gs.Ghost.AccuracyDelta(gs.Song, gs.NoteEvents, gs.AudioPosition())
+	if !ok {
+		return
+	}
+
+	const fontSize = 30
+
+	text := fmt.Sprintf("%+.2f%% vs best", delta*100)
+
+	fill := FnfColor{0x57, 0xE3, 0x13, 0xFF}
+	if delta < 0 {
+		fill = FnfColor{0xFF, 0x4D, 0x4D, 0xFF}
+	}
+
+	// draw it left of the hit error bar
+	y := float32(SCREEN_HEIGHT - 50)
+	if TheOptions.DownScroll {
+		y = 30
+	}
+
+	size := MeasureText(SdfFontClear, text, fontSize, 0)
+
+	DrawTextOutlined(
+		SdfFontClear, text,
+		rl.Vector2{SCREEN_WIDTH/2 - gs.HitErrorBar.HalfWidth - 30 - size.X, y - size.Y*0.5},
+		fontSize, 0,
+		ToRlColor(fill), ToRlColor(FnfColor{0, 0, 0, 255}), 4,
+	)
+}
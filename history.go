@@ -0,0 +1,165 @@
+package fnf
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"time"
+)
+
+type PlayRecordKind int
+
+const (
+	// played until the end and every note was judged
+	PlayRecordFull PlayRecordKind = iota
+	// played until the end but skipped some part of the song
+	PlayRecordPartial
+	// passes of a loop, one record for each time loop was set
+	PlayRecordLoop
+	PlayRecordKindSize
+)
+
+var PlayRecordKindStrs = [PlayRecordKindSize]string{
+	PlayRecordFull:    "full",
+	PlayRecordPartial: "partial",
+	PlayRecordLoop:    "loop",
+}
+
+type PlayRecord struct {
+	Date time.Time
+	Kind PlayRecordKind
+
+	AudioSpeed float64
+
+	Score    int
+	Accuracy float64
+
+	RatingCounts [HitRatingSize]int
+	MissCount    int
+	MaxCombo     int
+
+	// song time (doesn't include GSC.PadStart) of the loop
+	// only meaningful if Kind is PlayRecordLoop
+	LoopStart time.Duration
+	LoopEnd   time.Duration
+	// how many times loop was passed, rest of the record is from the best pass
+	LoopPasses int
+
+	// name of the replay file in replays directory, empty if replay wasn't saved
+	ReplayFile string
+}
+
+func NewPlayRecord(kind PlayRecordKind, stats PlayStats, audioSpeed float64) PlayRecord {
+	return PlayRecord{
+		Date: time.Now(),
+		Kind: kind,
+
+		AudioSpeed: audioSpeed,
+
+		Score:    stats.Score,
+		Accuracy: stats.Accuracy,
+
+		RatingCounts: stats.RatingCounts,
+		MissCount:    stats.MissCount,
+		MaxCombo:     stats.MaxCombo,
+	}
+}
+
+// Adds another pass to the loop record, keeping the better one's stats.
+// Date stays at when the first pass was made.
+func (r *PlayRecord) AddLoopPass(pass PlayRecord) {
+	if r.LoopPasses <= 0 {
+		*r = pass
+		r.LoopPasses = 1
+		return
+	}
+
+	passes := r.LoopPasses + 1
+	date := r.Date
+
+	if pass.IsBetterThan(*r) {
+		*r = pass
+	}
+
+	r.LoopPasses = passes
+	r.Date = date
+}
+
+// Returns true if r is a better play than other.
+func (r PlayRecord) IsBetterThan(other PlayRecord) bool {
+	if r.Accuracy != other.Accuracy {
+		return r.Accuracy > other.Accuracy
+	}
+	return r.Score > other.Score
+}
+
+// How many records we keep per chart.
+// Personal best is never thrown away.
+const PlayHistoryMax = 200
+
+// key is from SongDataKey(), records are from oldest to newest
+var ThePlayHistory = make(map[string][]PlayRecord)
+
+func GetPlayRecords(key string) []PlayRecord {
+	return slices.Clone(ThePlayHistory[key])
+}
+
+func AddPlayRecord(key string, record PlayRecord) {
+	records := append(ThePlayHistory[key], record)
+
+	for len(records) > PlayHistoryMax {
+		bestIndex, _ := findPersonalBest(records)
+
+		toRemove := 0
+		if toRemove == bestIndex {
+			toRemove = 1
+		}
+
+		// nothing refers to the replay anymore
+		if name := records[toRemove].ReplayFile; name != "" {
+			if err := DeleteReplay(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				ErrorLogger.Printf("failed to delete replay %v: %v", name, err)
+			}
+		}
+
+		records = slices.Delete(records, toRemove, toRemove+1)
+	}
+
+	ThePlayHistory[key] = records
+}
+
+// Runs slower than this don't count as personal best.
+const PersonalBestMinSpeed = 1.0
+
+// Returns the best full run of a chart that was played at normal speed or faster.
+func GetPersonalBest(key string) (PlayRecord, bool) {
+	records := ThePlayHistory[key]
+
+	index, ok := findPersonalBest(records)
+	if !ok {
+		return PlayRecord{}, false
+	}
+
+	return records[index], true
+}
+
+func findPersonalBest(records []PlayRecord) (int, bool) {
+	bestIndex := -1
+
+	for i, record := range records {
+		if record.Kind != PlayRecordFull {
+			continue
+		}
+
+		// small margin for float error
+		if record.AudioSpeed < PersonalBestMinSpeed-0.001 {
+			continue
+		}
+
+		if bestIndex < 0 || record.IsBetterThan(records[bestIndex]) {
+			bestIndex = i
+		}
+	}
+
+	return bestIndex, bestIndex >= 0
+}
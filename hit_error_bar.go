@@ -0,0 +1,213 @@
+package fnf
+
+import (
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type HitErrorTick struct {
+	// when tick was pushed (in real time)
+	Start time.Duration
+
+	// hit time - note time (in audio time)
+	//
+	// NOTE : unlike NotePopup.Diff, this is NOT scaled by audio speed
+	// because hit windows that we draw are in audio time
+	// and we want ticks to land inside the band of the rating they got
+	Diff time.Duration
+}
+
+// osu! style hit error bar
+//
+// draws hit windows as colored bands
+// and last N hit offsets as fading ticks
+type HitErrorBar struct {
+	Ticks CircularQueue[HitErrorTick]
+
+	// how many recent ticks are used to calculate moving average
+	AverageOf int
+
+	// how long ticks stays on screen
+	TickDuration time.Duration
+
+	// half width of the bar (i.e. width of the bad hit window)
+	HalfWidth float32
+
+	BandHeight float32
+	TickHeight float32
+	TickWidth  float32
+
+	BandColors [HitRatingSize]FnfColor
+
+	// marker position is animated toward the average
+	markerX    float32
+	markerInit bool
+}
+
+func NewHitErrorBar() *HitErrorBar {
+	hb := new(HitErrorBar)
+
+	hb.Ticks = CircularQueue[HitErrorTick]{
+		Data: make([]HitErrorTick, 32),
+	}
+
+	hb.AverageOf = 16
+	hb.TickDuration = time.Second * 4
+
+	hb.HalfWidth = 150
+	hb.BandHeight = 8
+	hb.TickHeight = 24
+	hb.TickWidth = 3
+
+	hb.BandColors = RatingColors
+
+	return hb
+}
+
+func (hb *HitErrorBar) Push(diff time.Duration) {
+	hb.Ticks.Enqueue(HitErrorTick{
+		Start: GlobalTimerNow(),
+		Diff:  diff,
+	})
+}
+
+func (hb *HitErrorBar) Clear() {
+	hb.Ticks.Clear()
+	hb.markerInit = false
+}
+
+// Returns average of last AverageOf ticks.
+// Returns false if there are no ticks.
+func (hb *HitErrorBar) Average() (time.Duration, bool) {
+	count := min(hb.Ticks.Length, hb.AverageOf)
+
+	if count <= 0 {
+		return 0, false
+	}
+
+	var sum time.Duration
+
+	for i := hb.Ticks.Length - count; i < hb.Ticks.Length; i++ {
+		sum += hb.Ticks.At(i).Diff
+	}
+
+	return sum / time.Duration(count), true
+}
+
+func (hb *HitErrorBar) maxWindow() time.Duration {
+	return max(
+		TheOptions.HitWindows[HitRatingBad],
+		TheOptions.HitWindows[HitRatingGood],
+		TheOptions.HitWindows[HitRatingSick],
+		time.Millisecond, // just in case
+	)
+}
+
+func (hb *HitErrorBar) diffToX(centerX float32, diff time.Duration) float32 {
+	x := centerX + f32(diff)/f32(hb.maxWindow())*hb.HalfWidth
+	return Clamp(x, centerX-hb.HalfWidth, centerX+hb.HalfWidth)
+}
+
+// Draws hit error bar. centerY is the center of the bands.
+func (hb *HitErrorBar) Draw(centerX, centerY float32) {
+	// remove ticks that are too old
+	for !hb.Ticks.IsEmpty() {
+		if TimeSinceNow(hb.Ticks.PeekFirst().Start) > hb.TickDuration {
+			hb.Ticks.Dequeue()
+		} else {
+			break
+		}
+	}
+
+	// =========================
+	// draw bands
+	// =========================
+
+	// draw from the widest to the narrowest
+	ratingOrder := [HitRatingSize]FnfHitRating{
+		HitRatingBad, HitRatingGood, HitRatingSick,
+	}
+
+	bgRect := rl.Rectangle{
+		X: centerX - hb.HalfWidth - 4, Y: centerY - hb.BandHeight*0.5 - 4,
+		Width: hb.HalfWidth*2 + 8, Height: hb.BandHeight + 8,
+	}
+
+	rl.DrawRectangleRounded(bgRect, 1, 5, ToRlColor(FnfColor{0, 0, 0, 150}))
+
+	for _, rating := range ratingOrder {
+		window := TheOptions.HitWindows[rating]
+		halfW := f32(window) / f32(hb.maxWindow()) * hb.HalfWidth
+
+		rl.DrawRectangleRec(
+			rl.Rectangle{
+				X: centerX - halfW, Y: centerY - hb.BandHeight*0.5,
+				Width: halfW * 2, Height: hb.BandHeight,
+			},
+			ToRlColor(hb.BandColors[rating]),
+		)
+	}
+
+	// draw center line
+	rl.DrawRectangleRec(
+		rl.Rectangle{
+			X: centerX - 1, Y: centerY - hb.TickHeight*0.5,
+			Width: 2, Height: hb.TickHeight,
+		},
+		ToRlColor(FnfColor{255, 255, 255, 255}),
+	)
+
+	// =========================
+	// draw ticks
+	// =========================
+	for i := range hb.Ticks.Length {
+		tick := hb.Ticks.At(i)
+
+		t := f32(TimeSinceNow(tick.Start)) / f32(hb.TickDuration)
+		t = Clamp(t, 0, 1)
+
+		alpha := 1 - EaseIn(t)
+
+		rating := GetHitRating(0, tick.Diff)
+		col := hb.BandColors[rating]
+		col.A = uint8(alpha * 255)
+
+		x := hb.diffToX(centerX, tick.Diff)
+
+		rl.DrawRectangleRec(
+			rl.Rectangle{
+				X: x - hb.TickWidth*0.5, Y: centerY - hb.TickHeight*0.5,
+				Width: hb.TickWidth, Height: hb.TickHeight,
+			},
+			ToRlColor(col),
+		)
+	}
+
+	// =========================
+	// draw average marker
+	// =========================
+	if avg, ok := hb.Average(); ok {
+		targetX := hb.diffToX(centerX, avg)
+
+		if !hb.markerInit {
+			hb.markerX = targetX
+			hb.markerInit = true
+		} else {
+			hb.markerX = Lerp(hb.markerX, targetX, 0.2)
+		}
+
+		const markerW = 16
+		const markerH = 12
+
+		markerY := centerY + hb.TickHeight*0.5 + 4
+
+		// NOTE : raylib wants vertices in counter-clockwise order
+		rl.DrawTriangle(
+			rl.Vector2{hb.markerX, markerY},
+			rl.Vector2{hb.markerX - markerW*0.5, markerY + markerH},
+			rl.Vector2{hb.markerX + markerW*0.5, markerY + markerH},
+			ToRlColor(FnfColor{255, 255, 255, 255}),
+		)
+	}
+}
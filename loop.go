@@ -0,0 +1,163 @@
+package fnf
+
+import (
+	"fmt"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func (gs *GameScreen) IsLoopActive() bool {
+	return gs.LoopStartSet && gs.LoopEndSet && gs.LoopStart < gs.LoopEnd
+}
+
+func (gs *GameScreen) SetLoopStart(at time.Duration) {
+	gs.LoopStart = at
+	gs.LoopStartSet = true
+
+	if gs.LoopEndSet && gs.LoopEnd <= gs.LoopStart {
+		gs.LoopEndSet = false
+	}
+
+	gs.resetLoopCounter()
+}
+
+func (gs *GameScreen) SetLoopEnd(at time.Duration) {
+	gs.LoopEnd = at
+	gs.LoopEndSet = true
+
+	if gs.LoopStartSet && gs.LoopStart >= gs.LoopEnd {
+		gs.LoopStartSet = false
+	}
+
+	gs.resetLoopCounter()
+}
+
+func (gs *GameScreen) ClearLoop() {
+	gs.LoopStartSet = false
+	gs.LoopEndSet = false
+	gs.isLoopDragging = false
+
+	gs.resetLoopCounter()
+}
+
+func (gs *GameScreen) resetLoopCounter() {
+	gs.flushLoopRecord()
+
+	gs.LoopCount = 0
+	gs.LoopAccuracies = gs.LoopAccuracies[:0]
+
+	// new section, start over
+	gs.SpeedTrainer.Reset()
+	if gs.SpeedTrainer.Enabled && gs.AudioSpeed() != gs.SpeedTrainer.Speed {
+		gs.SetAudioSpeed(gs.SpeedTrainer.Speed)
+	}
+}
+
+// Adds passes of the current loop to play history as a single record.
+func (gs *GameScreen) flushLoopRecord() {
+	if gs.loopRecord.LoopPasses <= 0 {
+		return
+	}
+
+	AddPlayRecord(gs.loopRecordKey, gs.loopRecord)
+	gs.loopRecord = PlayRecord{}
+
+	if err := SavePlayHistory(); err != nil {
+		ErrorLogger.Printf("failed to save play history: %v", err)
+		DisplayAlert("failed to save play history")
+	}
+}
+
+// Where we go back to when we reach loop end.
+// It's loop start minus pre-roll beats.
+func (gs *GameScreen) LoopRestartPosition() time.Duration {
+	bpm := songBpmAt(gs.Song, gs.LoopStart)
+	preRoll := BeatsToTime(f64(TheOptions.LoopPreRoll), bpm)
+
+	return max(gs.LoopStart-preRoll, 0)
+}
+
+func (gs *GameScreen) DrawLoopInfo() {
+	if !gs.IsLoopActive() {
+		return
+	}
+
+	const fontSize = 25
+
+	outRect := gs.ProgressBarOuterRect()
+
+	text := fmt.Sprintf("loop %d", gs.LoopCount)
+
+	if len(gs.LoopAccuracies) > 0 {
+		last := gs.LoopAccuracies[len(gs.LoopAccuracies)-1]
+		best := last
+
+		for _, acc := range gs.LoopAccuracies {
+			best = max(best, acc)
+		}
+
+		text += fmt.Sprintf("  last %.2f%%  best %.2f%%", last*100, best*100)
+	}
+
+	if gs.SpeedTrainer.Enabled {
+		st := gs.SpeedTrainer
+
+		text += fmt.Sprintf("\ntrainer %.2fx  %d/%d", st.Speed, st.CleanPasses, TheOptions.SpeedTrainerPasses)
+
+		if st.HighestCleared > 0 {
+			text += fmt.Sprintf("  cleared %.2fx", st.HighestCleared)
+		}
+
+		if st.Finished {
+			text += "  done!"
+		}
+	}
+
+	pos := rl.Vector2{
+		X: outRect.X + outRect.Width + 15,
+		Y: outRect.Y + outRect.Height*0.5 - fontSize*0.5,
+	}
+
+	rl.SetTextLineSpacing(fontSize)
+
+	// grow upward when progress bar is at the bottom
+	if TheOptions.DownScroll {
+		textSize := MeasureText(SdfFontClear, text, fontSize, 0)
+		pos.Y -= textSize.Y - fontSize
+	}
+
+	DrawTextOutlined(
+		SdfFontClear, text, pos, fontSize, 0,
+		ToRlColor(FnfColor{255, 255, 255, 255}), ToRlColor(FnfColor{0, 0, 0, 255}), 3,
+	)
+}
+
+// Records how well we did on the loop we just played through.
+func (gs *GameScreen) recordLoopPass() {
+	stats := CalculatePlayStats(
+		gs.Song, gs.NoteEvents, gs.mainPlayer(), gs.LoopStart, gs.LoopEnd)
+
+	if stats.JudgedCount <= 0 || gs.IsBotPlay() || gs.IsReplaying() {
+		return
+	}
+
+	gs.LoopCount++
+	gs.LoopAccuracies = append(gs.LoopAccuracies, stats.Accuracy)
+
+	record := NewPlayRecord(PlayRecordLoop, stats, gs.AudioSpeed())
+	record.LoopStart = gs.LoopStart - GSC.PadStart
+	record.LoopEnd = gs.LoopEnd - GSC.PadStart
+
+	// difficulty might have changed while looping
+	key := SongDataKey(gs.PathGroup, gs.SelectedDifficulty)
+	if key != gs.loopRecordKey {
+		gs.flushLoopRecord()
+		gs.loopRecordKey = key
+	}
+	gs.loopRecord.AddLoopPass(record)
+
+	if gs.SpeedTrainer.Enabled {
+		gs.speedTrainerPass(stats)
+	}
+}
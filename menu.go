@@ -597,6 +597,11 @@ func (md *MenuDrawer) Update(deltaTime time.Duration) {
 			// handle select key interaction
 			// ===================================
 			if AreKeysPressed(md.InputId, TheKM[SelectKey]) {
+				switch selected.Type {
+				case MenuItemTrigger, MenuItemToggle, MenuItemKey:
+					PlaySoundEffect(SoundEffectMenuSelect)
+				}
+
 				switch selected.Type {
 				case MenuItemTrigger:
 					selected.BValue = true
@@ -742,6 +747,7 @@ func (md *MenuDrawer) Update(deltaTime time.Duration) {
 
 	if md.selectedIndex != prevSelected {
 		md.scrollAnimT = 0
+		PlaySoundEffect(SoundEffectMenuMove)
 	}
 
 	// but I have a strong feeling that this is not frame indipendent
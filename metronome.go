@@ -0,0 +1,250 @@
+package fnf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+// Generates a short decaying sine wave click.
+func GenerateClickAudio(freq float64) []byte {
+	clickLength := SampleRate * 30 / 1000 // 30ms
+
+	audio := make([]byte, clickLength*BytesPerSample)
+
+	for i := range clickLength {
+		t := f64(i) / SampleRate
+
+		envelope := 1 - f64(i)/f64(clickLength)
+		envelope *= envelope
+
+		v := int16(math.Sin(2*math.Pi*freq*t) * envelope * 0.6 * math.MaxInt16)
+
+		at := i * BytesPerSample
+
+		// left and right channel
+		binary.LittleEndian.PutUint16(audio[at:], uint16(v))
+		binary.LittleEndian.PutUint16(audio[at+2:], uint16(v))
+	}
+
+	return audio
+}
+
+// A beat that metronome clicks on.
+type ClickBeat struct {
+	At     time.Duration
+	Accent bool
+}
+
+// Returns every beat of the song until given time.
+//
+// Like GameScreen.MeasureAt, beats are counted from GSC.PadStart
+// so every 4th beat from it is a downbeat.
+// Beats before GSC.PadStart use bpm at GSC.PadStart.
+func SongBeats(song FnfSong, until time.Duration) []ClickBeat {
+	var beats []ClickBeat
+
+	beatLength := BeatsToTime(1, songBpmAt(song, GSC.PadStart))
+
+	n := GSC.PadStart / beatLength
+
+	for i := n; i > 0; i-- {
+		beats = append(beats, ClickBeat{
+			At:     GSC.PadStart - i*beatLength,
+			Accent: i%4 == 0,
+		})
+	}
+
+	pos := GSC.PadStart
+	index := 0
+
+	for pos < until {
+		beats = append(beats, ClickBeat{At: pos, Accent: index%4 == 0})
+
+		pos += BeatsToTime(1, songBpmAt(song, pos))
+		index++
+	}
+
+	return beats
+}
+
+// Returns byte position of the sample nearest to t.
+func clickBytePosition(t time.Duration) int64 {
+	return (int64(t)*SampleRate + int64(time.Second/2)) / int64(time.Second) * BytesPerSample
+}
+
+// Clicks that are mixed in at fixed positions of a stream.
+type clickTrack struct {
+	// byte positions of beats, sorted
+	beats   []int64
+	accents []bool
+
+	click  []byte
+	accent []byte
+
+	// index of the next beat to click on
+	next int
+
+	// click that is playing, nil if there isn't one
+	playing   []byte
+	playingAt int
+}
+
+func newClickTrack(beats []ClickBeat, click, accent []byte) *clickTrack {
+	ct := new(clickTrack)
+
+	ct.click = click
+	ct.accent = accent
+
+	for _, beat := range beats {
+		ct.beats = append(ct.beats, clickBytePosition(beat.At))
+		ct.accents = append(ct.accents, beat.Accent)
+	}
+
+	return ct
+}
+
+func (ct *clickTrack) seek(bytePosition int64) {
+	ct.next, _ = slices.BinarySearch(ct.beats, bytePosition)
+	ct.playing = nil
+}
+
+func newClickStream(clicks *clickTrack, length int64) *VaryingSpeedStream {
+	vs := new(VaryingSpeedStream)
+	vs.speed = 1.0
+
+	vs.length = length - length%BytesPerSample
+	vs.decodedBytesSize = vs.length
+
+	vs.clicks = clicks
+
+	return vs
+}
+
+// Writes clicks to p.
+//
+// Clicks start on the first sample at or after the beat and they are not affected by speed.
+func (vs *VaryingSpeedStream) readClicks(p []byte) (int, error) {
+	ct := vs.clicks
+
+	wCursor := 0
+	wCursorLimit := (len(p) / BytesPerSample) * BytesPerSample
+
+	for {
+		if vs.bytePosition+BytesPerSample >= vs.audioBytesSize() {
+			return wCursor, io.EOF
+		}
+
+		if wCursor+BytesPerSample >= wCursorLimit {
+			return wCursor, nil
+		}
+
+		// at high speed we might go past more than one beat in a sample
+		for ct.next < len(ct.beats) && ct.beats[ct.next] <= vs.bytePosition {
+			if ct.accents[ct.next] {
+				ct.playing = ct.accent
+			} else {
+				ct.playing = ct.click
+			}
+			ct.playingAt = 0
+
+			ct.next++
+		}
+
+		sample := p[wCursor : wCursor+BytesPerSample]
+
+		if ct.playing != nil {
+			copy(sample, ct.playing[ct.playingAt:])
+
+			ct.playingAt += BytesPerSample
+			if ct.playingAt >= len(ct.playing) {
+				ct.playing = nil
+			}
+		} else {
+			clear(sample)
+		}
+
+		wCursor += BytesPerSample
+
+		vs.advance()
+	}
+}
+
+type Metronome struct {
+	clickPlayers  []*VaryingSpeedPlayer
+	accentPlayers []*VaryingSpeedPlayer
+
+	playerIndex int
+
+	// plays clicks on beats of the song
+	// it should be played, paused and moved along with the song
+	SongPlayer *VaryingSpeedPlayer
+
+	click  []byte
+	accent []byte
+}
+
+func NewMetronome() *Metronome {
+	m := new(Metronome)
+
+	click := GenerateClickAudio(1000)
+	accent := GenerateClickAudio(1500)
+
+	m.click = click
+	m.accent = accent
+
+	for range 4 {
+		clickPlayer := NewVaryingSpeedPlayer(0, 0)
+		clickPlayer.LoadDecodedAudio(click)
+		clickPlayer.SetMixerTrack(MixerTrackMetronome)
+		m.clickPlayers = append(m.clickPlayers, clickPlayer)
+
+		accentPlayer := NewVaryingSpeedPlayer(0, 0)
+		accentPlayer.LoadDecodedAudio(accent)
+		accentPlayer.SetMixerTrack(MixerTrackMetronome)
+		m.accentPlayers = append(m.accentPlayers, accentPlayer)
+	}
+
+	return m
+}
+
+func (m *Metronome) PlayClick(accent bool, volume float64) {
+	if volume < 0.001 { // just in case
+		return
+	}
+
+	players := m.clickPlayers
+	if accent {
+		players = m.accentPlayers
+	}
+
+	m.playerIndex = (m.playerIndex + 1) % len(players)
+
+	player := players[m.playerIndex]
+
+	player.SetVolume(volume)
+	player.Rewind()
+	player.Play()
+}
+
+// Places clicks on beats of the song.
+//
+// duration should be the duration of the song audio including paddings.
+func (m *Metronome) LoadSong(song FnfSong, duration time.Duration) {
+	if m.SongPlayer == nil {
+		m.SongPlayer = NewVaryingSpeedPlayer(0, 0)
+		m.SongPlayer.SetMixerTrack(MixerTrackMetronome)
+	}
+
+	m.SongPlayer.LoadClicks(SongBeats(song, duration), m.click, m.accent, duration)
+	m.SongPlayer.SetVolume(TheOptions.MetronomeVolume)
+}
+
+// Updates volume of SongPlayer in case options changed.
+func (m *Metronome) Update() {
+	if m.SongPlayer != nil && m.SongPlayer.Volume() != TheOptions.MetronomeVolume {
+		m.SongPlayer.SetVolume(TheOptions.MetronomeVolume)
+	}
+}
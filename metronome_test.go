@@ -0,0 +1,109 @@
+package fnf
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+var (
+	testClick  = []byte{1, 0, 1, 0, 1, 0, 1, 0}
+	testAccent = []byte{2, 0, 2, 0, 2, 0, 2, 0}
+)
+
+func testSampleTime(sample int64) time.Duration {
+	return time.Duration(sample) * time.Second / SampleRate
+}
+
+type testClickSound struct {
+	at    int // output sample where click starts
+	value byte
+}
+
+// Plays click track from seekTo at given speed
+// and returns where each click sound starts in the output.
+func testPlayClicks(t *testing.T, beats []ClickBeat, speed float64, seekTo int64) []testClickSound {
+	t.Helper()
+
+	vs := newClickStream(newClickTrack(beats, testClick, testAccent), 1000*BytesPerSample)
+	vs.SetSpeed(speed)
+
+	if _, err := vs.Seek(seekTo*BytesPerSample, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 1000*BytesPerSample)
+	n, _ := vs.Read(out)
+
+	var clicks []testClickSound
+
+	for i := 0; i+BytesPerSample <= n; i += BytesPerSample {
+		if out[i] == 0 {
+			continue
+		}
+		if i > 0 && out[i-BytesPerSample] != 0 {
+			continue
+		}
+
+		clicks = append(clicks, testClickSound{at: i / BytesPerSample, value: out[i]})
+	}
+
+	return clicks
+}
+
+func testExpectClicks(t *testing.T, got []testClickSound, want ...testClickSound) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("clicks %v, want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("click %v is %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestClickStreamPlaysBeatsOnTime(t *testing.T) {
+	beats := []ClickBeat{
+		{At: testSampleTime(100), Accent: true},
+		{At: testSampleTime(300)},
+	}
+
+	testExpectClicks(t, testPlayClicks(t, beats, 1, 0),
+		testClickSound{at: 100, value: 2},
+		testClickSound{at: 300, value: 1},
+	)
+}
+
+// Clicks should stay on the beat of the song when it's sped up or slowed down.
+func TestClickStreamFollowsSpeed(t *testing.T) {
+	beats := []ClickBeat{
+		{At: testSampleTime(150)},
+		{At: testSampleTime(300), Accent: true},
+	}
+
+	testExpectClicks(t, testPlayClicks(t, beats, 1.5, 0),
+		testClickSound{at: 100, value: 1},
+		testClickSound{at: 200, value: 2},
+	)
+
+	beats = []ClickBeat{{At: testSampleTime(100)}}
+
+	testExpectClicks(t, testPlayClicks(t, beats, 0.5, 0),
+		testClickSound{at: 200, value: 1},
+	)
+}
+
+func TestClickStreamSeek(t *testing.T) {
+	beats := []ClickBeat{
+		{At: testSampleTime(100), Accent: true},
+		{At: testSampleTime(300)},
+	}
+
+	// first beat was before where we seeked to
+	testExpectClicks(t, testPlayClicks(t, beats, 1, 200),
+		testClickSound{at: 100, value: 1},
+	)
+}
@@ -0,0 +1,44 @@
+package fnf
+
+// Tracks that can be turned up, down or muted separately.
+type MixerTrack int
+
+const (
+	MixerTrackInst MixerTrack = iota
+	MixerTrackVoice
+	MixerTrackHitSound
+	// metronome and count-in clicks
+	MixerTrackMetronome
+	// sounds played when moving around in menus
+	MixerTrackMenu
+	MixerTrackSize
+
+	// players that mixer doesn't touch
+	MixerTrackNone MixerTrack = -1
+)
+
+var MixerTrackNames = [MixerTrackSize]string{
+	MixerTrackInst:      "Inst",
+	MixerTrackVoice:     "Voice",
+	MixerTrackHitSound:  "Hit Sound",
+	MixerTrackMetronome: "Metronome",
+	MixerTrackMenu:      "Menu Sound",
+}
+
+type MixerChannel struct {
+	Volume float64
+	Mute   bool
+}
+
+// Returns how much player on the track should be scaled by.
+func mixerVolume(mixer [MixerTrackSize]MixerChannel, track MixerTrack) float64 {
+	if track < 0 || track >= MixerTrackSize {
+		return 1
+	}
+
+	if mixer[track].Mute {
+		return 0
+	}
+
+	return Clamp(mixer[track].Volume, 0, 1)
+}
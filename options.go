@@ -38,6 +38,18 @@ const (
 	SetBookMarkKey
 	JumpToBookMarkKey
 
+	PrevBookMarkKey
+	NextBookMarkKey
+	RenameBookMarkKey
+
+	LoopStartKey
+	LoopEndKey
+	ClearLoopKey
+
+	NextTroubleSpotKey
+
+	ReviewModeKey
+
 	ZoomOutKey
 	ZoomInKey
 
@@ -92,6 +104,18 @@ func init() {
 	DefaultKM[SetBookMarkKey] = rl.KeyB
 	DefaultKM[JumpToBookMarkKey] = rl.KeyBackspace
 
+	DefaultKM[PrevBookMarkKey] = rl.KeyComma
+	DefaultKM[NextBookMarkKey] = rl.KeyPeriod
+	DefaultKM[RenameBookMarkKey] = rl.KeyN
+
+	DefaultKM[LoopStartKey] = rl.KeyOne
+	DefaultKM[LoopEndKey] = rl.KeyTwo
+	DefaultKM[ClearLoopKey] = rl.KeyThree
+
+	DefaultKM[NextTroubleSpotKey] = rl.KeyT
+
+	DefaultKM[ReviewModeKey] = rl.KeyV
+
 	DefaultKM[ZoomOutKey] = rl.KeyLeftBracket
 	DefaultKM[ZoomInKey] = rl.KeyRightBracket
 
@@ -142,6 +166,18 @@ func init() {
 	KeyHumanName[SetBookMarkKey] = "bookmark"
 	KeyHumanName[JumpToBookMarkKey] = "jump to bookmark"
 
+	KeyHumanName[PrevBookMarkKey] = "previous bookmark"
+	KeyHumanName[NextBookMarkKey] = "next bookmark"
+	KeyHumanName[RenameBookMarkKey] = "rename bookmark"
+
+	KeyHumanName[LoopStartKey] = "set loop start"
+	KeyHumanName[LoopEndKey] = "set loop end"
+	KeyHumanName[ClearLoopKey] = "clear loop"
+
+	KeyHumanName[NextTroubleSpotKey] = "next trouble spot"
+
+	KeyHumanName[ReviewModeKey] = "review mode"
+
 	KeyHumanName[ZoomOutKey] = "note spacing up"
 	KeyHumanName[ZoomInKey] = "note spacing down"
 
@@ -228,6 +264,22 @@ func NoteKeysArr() [NoteDirSize][]int32 {
 	}
 }
 
+type HeatmapMode int
+
+const (
+	HeatmapOff HeatmapMode = iota
+	HeatmapByMeasure
+	// splits the song where mustHitSection changes
+	HeatmapByMustHit
+	HeatmapModeSize
+)
+
+var HeatmapModeNames = [HeatmapModeSize]string{
+	HeatmapOff:       "Off",
+	HeatmapByMeasure: "Measure",
+	HeatmapByMustHit: "Must Hit Section",
+}
+
 type Options struct {
 	TargetFPS int32
 
@@ -241,21 +293,79 @@ type Options struct {
 
 	LoadAudioDuringGamePlay bool
 
+	// size limit of decoded audio cache in megabytes, 0 means cache is off
+	AudioCacheSize int
+
+	// decode audio while it's played rather than keeping the whole song in memory
+	StreamAudio bool
+
 	GhostTapping bool
 
 	MiddleScroll bool
 
 	HitSoundVolume float64
 
+	// name of the sound pack for hit, miss and menu sounds
+	SoundPack string
+
+	// 0 means metronome is off
+	MetronomeVolume float64
+
+	// volume and mute for each track, applied on top of other volumes
+	Mixer [MixerTrackSize]MixerChannel
+
+	// play a measure of clicks before resuming
+	CountIn bool
+
+	// keep the pitch when audio is slowed down or sped up
+	PreservePitch bool
+
 	DisplayHitMs bool
 
 	NoteSplash bool
 
 	AudioOffset time.Duration
+
+	// shifts only when key presses are judged
+	// used to compensate for input and display latency
+	InputOffset time.Duration
+
+	HitErrorBar bool
+
+	// show personal best run while playing
+	Ghost bool
+
+	SnapBookMarks bool
+
+	// how many beats to play before loop start when looping
+	LoopPreRoll int
+
+	// how progress bar is split to show accuracy of each part
+	ProgressBarHeatmap HeatmapMode
+
+	// speed trainer
+	SpeedTrainerStart  float64
+	SpeedTrainerStep   float64
+	SpeedTrainerTarget float64
+	// how many clean passes are needed to go up
+	SpeedTrainerPasses int
 }
 
 const AudioOffsetMax time.Duration = 500 * time.Millisecond
 
+const InputOffsetMax time.Duration = 200 * time.Millisecond
+
+const LoopPreRollMax = 16
+
+const AudioCacheSizeMax = 8192
+
+const (
+	SpeedTrainerSpeedMin = 0.1
+	SpeedTrainerSpeedMax = 2.0
+
+	SpeedTrainerPassesMax = 10
+)
+
 var DefaultOptions Options
 
 var TheOptions Options
@@ -275,17 +385,44 @@ func init() {
 
 	DefaultOptions.LoadAudioDuringGamePlay = false
 
+	DefaultOptions.AudioCacheSize = 256
+
+	DefaultOptions.StreamAudio = false
+
 	DefaultOptions.GhostTapping = false
 
 	DefaultOptions.MiddleScroll = false
 
 	DefaultOptions.HitSoundVolume = 0
+	DefaultOptions.SoundPack = SoundPackDefault
+	DefaultOptions.MetronomeVolume = 0
+	for track := MixerTrack(0); track < MixerTrackSize; track++ {
+		DefaultOptions.Mixer[track] = MixerChannel{Volume: 1}
+	}
+	DefaultOptions.CountIn = false
+	DefaultOptions.PreservePitch = false
 
 	DefaultOptions.DisplayHitMs = false
 
 	DefaultOptions.NoteSplash = true
 
 	DefaultOptions.AudioOffset = 0
+	DefaultOptions.InputOffset = 0
+
+	DefaultOptions.HitErrorBar = true
+
+	DefaultOptions.Ghost = true
+
+	DefaultOptions.SnapBookMarks = false
+
+	DefaultOptions.LoopPreRoll = 4
+
+	DefaultOptions.ProgressBarHeatmap = HeatmapOff
+
+	DefaultOptions.SpeedTrainerStart = 0.7
+	DefaultOptions.SpeedTrainerStep = 0.05
+	DefaultOptions.SpeedTrainerTarget = 1.0
+	DefaultOptions.SpeedTrainerPasses = 3
 
 	// set TheOptions to DefaultOptions
 	TheOptions = DefaultOptions
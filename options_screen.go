@@ -162,6 +162,11 @@ func (op *BaseOptionsScreen) BeforeScreenEnd() {
 		DisplayAlert("failed to save settings")
 	}
 
+	// cache size might have gotten smaller
+	if err := EvictAudioCache(AudioCacheLimit()); err != nil {
+		ErrorLogger.Printf("failed to evict audio cache: %v", err)
+	}
+
 	if op.selectFirstItem {
 		op.Menu.SelectItemAt(0, false) // select first item
 		op.selectFirstItem = false
@@ -264,6 +269,56 @@ func NewOptionsMainScreen() *BaseOptionsScreen {
 		`Load audio during game play. May cause some issues and definitely not recommended if you use slow PC.`,
 	)
 
+	streamAudioItem := NewMenuItem()
+	streamAudioItem.Name = "Stream Audio"
+	streamAudioItem.Type = MenuItemToggle
+	streamAudioItem.ToggleCallback = func(bValue bool) {
+		TheOptions.StreamAudio = bValue
+	}
+	op.Menu.AddItems(streamAudioItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemBValue(streamAudioItem.Id, false, TheOptions.StreamAudio)
+	})
+
+	op.AddHelpMessageTopRight(streamAudioItem.Id,
+		50, 50, 460,
+		`Decode songs little by little while they play instead of keeping them in memory. Uses much less memory for long songs. Opus files and flac files that aren't 44100Hz are loaded whole, and audio cache isn't used while streaming.`,
+	)
+
+	audioCacheItem := NewMenuItem()
+	audioCacheItem.Name = "Audio Cache Size"
+	audioCacheItem.Type = MenuItemNumber
+	audioCacheItem.NValue = f32(TheOptions.AudioCacheSize)
+	audioCacheItem.NValueMin = 0
+	audioCacheItem.NValueMax = AudioCacheSizeMax
+	audioCacheItem.NValueInterval = 256
+	audioCacheItem.NValueFmtString = "%1.f MB"
+	audioCacheItem.NumberCallback = func(nValue float32) {
+		TheOptions.AudioCacheSize = int(nValue)
+	}
+	op.Menu.AddItems(audioCacheItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemNvalue(audioCacheItem.Id, false, f32(TheOptions.AudioCacheSize))
+	})
+
+	op.AddHelpMessageTopRight(audioCacheItem.Id,
+		50, 50, 460,
+		`Keeps decoded songs on disk so that they open faster next time. Set it to 0 to turn it off.`,
+	)
+
+	clearAudioCacheItem := NewMenuItem()
+	clearAudioCacheItem.Name = "Clear Audio Cache"
+	clearAudioCacheItem.Type = MenuItemTrigger
+	clearAudioCacheItem.TriggerCallback = func() {
+		if err := ClearAudioCache(); err != nil {
+			ErrorLogger.Printf("failed to clear audio cache: %v", err)
+			DisplayAlert("failed to clear audio cache")
+		} else {
+			DisplayAlert("cleared audio cache")
+		}
+	}
+	op.Menu.AddItems(clearAudioCacheItem)
+
 	gamePlayItem := NewMenuItem()
 	gamePlayItem.Name = "Game Play"
 	gamePlayItem.Type = MenuItemTrigger
@@ -356,6 +411,41 @@ func NewOptionsGamePlayScreen() *BaseOptionsScreen {
 		op.Menu.SetItemNvalue(audioOffsetItem.Id, false, f32(TheOptions.AudioOffset/time.Millisecond))
 	})
 
+	inputOffsetItem := NewMenuItem()
+	inputOffsetItem.Name = "Input Offset"
+	inputOffsetItem.Type = MenuItemNumber
+	inputOffsetItem.NValue = float32(TheOptions.InputOffset)
+	inputOffsetItem.NValueMin = 0
+	inputOffsetItem.NValueMax = f32(InputOffsetMax / time.Millisecond)
+	inputOffsetItem.NValueInterval = 1
+	inputOffsetItem.NValueFmtString = "%1.f"
+	inputOffsetItem.LeftRightKeyRepeatRate = time.Millisecond * 10
+	inputOffsetItem.NumberCallback = func(nValue float32) {
+		TheOptions.InputOffset = time.Duration(nValue) * time.Millisecond
+	}
+	op.Menu.AddItems(inputOffsetItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemNvalue(inputOffsetItem.Id, false, f32(TheOptions.InputOffset/time.Millisecond))
+	})
+
+	op.AddHelpMessageTopRight(inputOffsetItem.Id,
+		50, 50, 460,
+		`Only changes when your key presses are judged, not where notes are drawn. Use it for keyboard and display latency.`,
+	)
+
+	calibrateItem := NewMenuItem()
+	calibrateItem.Name = "Calibrate Offset"
+	calibrateItem.Type = MenuItemTrigger
+	calibrateItem.TriggerCallback = func() {
+		SetNextScreen(TheCalibrationScreen)
+	}
+	op.Menu.AddItems(calibrateItem)
+
+	op.AddHelpMessageTopRight(calibrateItem.Id,
+		50, 50, 460,
+		`Tap along to a click track to find out audio offset. Visual test finds out input offset.`,
+	)
+
 	downScrollItem := NewMenuItem()
 	downScrollItem.Name = "Down Scroll"
 	downScrollItem.Type = MenuItemToggle
@@ -400,9 +490,6 @@ func NewOptionsGamePlayScreen() *BaseOptionsScreen {
 		op.Menu.SetItemBValue(noteSplash.Id, false, TheOptions.NoteSplash)
 	})
 
-	hitSoundPlayer := NewVaryingSpeedPlayer(0, 0)
-	hitSoundPlayer.LoadDecodedAudio(HitSoundAudio)
-
 	hitSoundItem := NewMenuItem()
 	hitSoundItem.Name = "Hit Sound"
 	hitSoundItem.Type = MenuItemNumber
@@ -415,18 +502,93 @@ func NewOptionsGamePlayScreen() *BaseOptionsScreen {
 		volume := float64(nValue) / 10
 
 		TheOptions.HitSoundVolume = volume
-		hitSoundPlayer.SetVolume(volume)
 
-		if volume > 0.001 { // just in case
-			hitSoundPlayer.Rewind()
-			hitSoundPlayer.Play()
-		}
+		PlaySoundEffect(SoundEffectHit)
 	}
 	op.Menu.AddItems(hitSoundItem)
 	op.OnMatchItemsToOption(func() {
 		op.Menu.SetItemNvalue(hitSoundItem.Id, false, float32(TheOptions.HitSoundVolume)*10)
 	})
 
+	soundPackItem := NewMenuItem()
+	soundPackItem.Name = "Sound Pack"
+	soundPackItem.Type = MenuItemList
+	soundPackItem.ListCallback = func(selected int, list []string) {
+		TheOptions.SoundPack = list[selected]
+		UpdateSoundPack()
+
+		PreviewSoundPack()
+	}
+	op.Menu.AddItems(soundPackItem)
+	op.OnMatchItemsToOption(func() {
+		// user might have added packs while the game is running
+		packs, err := ListSoundPacks()
+		if err != nil {
+			ErrorLogger.Printf("failed to list sound packs: %v", err)
+		}
+
+		UpdateSoundPack()
+
+		selected := max(slices.Index(packs, TheOptions.SoundPack), 0)
+		op.Menu.SetItemList(soundPackItem.Id, packs, selected)
+	})
+
+	op.AddHelpMessageTopRight(soundPackItem.Id,
+		50, 50, 460,
+		`Sounds played on hits, misses and in menus. Put your own packs in fnf-practice-sound-packs folder next to the game, see change-hit-sound.txt for how.`,
+	)
+
+	preservePitchItem := NewMenuItem()
+	preservePitchItem.Name = "Preserve Pitch"
+	preservePitchItem.Type = MenuItemToggle
+	preservePitchItem.ToggleCallback = func(bValue bool) {
+		TheOptions.PreservePitch = bValue
+	}
+	op.Menu.AddItems(preservePitchItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemBValue(preservePitchItem.Id, false, TheOptions.PreservePitch)
+	})
+
+	op.AddHelpMessageTopRight(preservePitchItem.Id,
+		50, 50, 460,
+		`Keep the pitch when song is slowed down or sped up. Uses more CPU.`,
+	)
+
+	metronome := NewMetronome()
+
+	metronomeItem := NewMenuItem()
+	metronomeItem.Name = "Metronome"
+	metronomeItem.Type = MenuItemNumber
+	metronomeItem.NValue = float32(TheOptions.MetronomeVolume)
+	metronomeItem.NValueMin = 0
+	metronomeItem.NValueMax = 10
+	metronomeItem.NValueInterval = 1
+	metronomeItem.NValueFmtString = "%1.f"
+	metronomeItem.NumberCallback = func(nValue float32) {
+		TheOptions.MetronomeVolume = float64(nValue) / 10
+		metronome.PlayClick(true, TheOptions.MetronomeVolume)
+	}
+	op.Menu.AddItems(metronomeItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemNvalue(metronomeItem.Id, false, float32(TheOptions.MetronomeVolume)*10)
+	})
+
+	countInItem := NewMenuItem()
+	countInItem.Name = "Count-In"
+	countInItem.Type = MenuItemToggle
+	countInItem.ToggleCallback = func(bValue bool) {
+		TheOptions.CountIn = bValue
+	}
+	op.Menu.AddItems(countInItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemBValue(countInItem.Id, false, TheOptions.CountIn)
+	})
+
+	op.AddHelpMessageTopRight(countInItem.Id,
+		50, 50, 460,
+		`Play a measure of clicks before song resumes from a pause, a rewind or a loop restart.`,
+	)
+
 	displayHitMsItem := NewMenuItem()
 	displayHitMsItem.Name = "Display Hit ms"
 	displayHitMsItem.Type = MenuItemToggle
@@ -438,6 +600,142 @@ func NewOptionsGamePlayScreen() *BaseOptionsScreen {
 		op.Menu.SetItemBValue(displayHitMsItem.Id, false, TheOptions.DisplayHitMs)
 	})
 
+	snapBookMarksItem := NewMenuItem()
+	snapBookMarksItem.Name = "Snap Bookmarks To Measure"
+	snapBookMarksItem.Type = MenuItemToggle
+	snapBookMarksItem.ToggleCallback = func(bValue bool) {
+		TheOptions.SnapBookMarks = bValue
+	}
+	op.Menu.AddItems(snapBookMarksItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemBValue(snapBookMarksItem.Id, false, TheOptions.SnapBookMarks)
+	})
+
+	loopPreRollItem := NewMenuItem()
+	loopPreRollItem.Name = "Loop Pre-Roll Beats"
+	loopPreRollItem.Type = MenuItemNumber
+	loopPreRollItem.NValue = f32(TheOptions.LoopPreRoll)
+	loopPreRollItem.NValueMin = 0
+	loopPreRollItem.NValueMax = LoopPreRollMax
+	loopPreRollItem.NValueInterval = 1
+	loopPreRollItem.NValueFmtString = "%1.f"
+	loopPreRollItem.NumberCallback = func(nValue float32) {
+		TheOptions.LoopPreRoll = int(nValue)
+	}
+	op.Menu.AddItems(loopPreRollItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemNvalue(loopPreRollItem.Id, false, f32(TheOptions.LoopPreRoll))
+	})
+
+	heatmapItem := NewMenuItem()
+	heatmapItem.Name = "Progress Bar Heatmap"
+	heatmapItem.Type = MenuItemList
+	heatmapItem.List = HeatmapModeNames[:]
+	heatmapItem.ListSelected = int(TheOptions.ProgressBarHeatmap)
+	heatmapItem.ListCallback = func(selected int, list []string) {
+		TheOptions.ProgressBarHeatmap = HeatmapMode(selected)
+	}
+	op.Menu.AddItems(heatmapItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemListSelected(heatmapItem.Id, false, int(TheOptions.ProgressBarHeatmap))
+	})
+
+	hitErrorBarItem := NewMenuItem()
+	hitErrorBarItem.Name = "Hit Error Bar"
+	hitErrorBarItem.Type = MenuItemToggle
+	hitErrorBarItem.ToggleCallback = func(bValue bool) {
+		TheOptions.HitErrorBar = bValue
+	}
+	op.Menu.AddItems(hitErrorBarItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemBValue(hitErrorBarItem.Id, false, TheOptions.HitErrorBar)
+	})
+
+	ghostItem := NewMenuItem()
+	ghostItem.Name = "Ghost Of Best Run"
+	ghostItem.Type = MenuItemToggle
+	ghostItem.ToggleCallback = func(bValue bool) {
+		TheOptions.Ghost = bValue
+	}
+	op.Menu.AddItems(ghostItem)
+	op.OnMatchItemsToOption(func() {
+		op.Menu.SetItemBValue(ghostItem.Id, false, TheOptions.Ghost)
+	})
+
+	// ================================
+	// add speed trainer options
+	// ================================
+	{
+		deco := NewMenuItem()
+		deco.Name = "Speed Trainer"
+		deco.Type = MenuItemDeco
+		deco.SizeRegular = MenuItemDefaults.SizeRegular * 1.4
+		deco.SizeSelected = MenuItemDefaults.SizeSelected * 1.4
+		deco.Color = FnfColor{0xFC, 0x9F, 0x7C, 0xFF}
+		deco.FadeIfUnselected = false
+		op.Menu.AddItems(deco)
+
+		newSpeedItem := func(name string, getter func() float64, setter func(float64)) {
+			item := NewMenuItem()
+			item.Name = name
+			item.Type = MenuItemNumber
+			item.NValue = f32(getter())
+			item.NValueMin = SpeedTrainerSpeedMin
+			item.NValueMax = SpeedTrainerSpeedMax
+			item.NValueInterval = 0.05
+			item.NValueFmtString = "%.2f"
+			item.NumberCallback = func(nValue float32) {
+				setter(f64(nValue))
+			}
+			op.Menu.AddItems(item)
+			op.OnMatchItemsToOption(func() {
+				op.Menu.SetItemNvalue(item.Id, false, f32(getter()))
+			})
+		}
+
+		newSpeedItem("Start Speed",
+			func() float64 { return TheOptions.SpeedTrainerStart },
+			func(v float64) { TheOptions.SpeedTrainerStart = v },
+		)
+
+		newSpeedItem("Target Speed",
+			func() float64 { return TheOptions.SpeedTrainerTarget },
+			func(v float64) { TheOptions.SpeedTrainerTarget = v },
+		)
+
+		stepItem := NewMenuItem()
+		stepItem.Name = "Speed Step"
+		stepItem.Type = MenuItemNumber
+		stepItem.NValue = f32(TheOptions.SpeedTrainerStep)
+		stepItem.NValueMin = 0.01
+		stepItem.NValueMax = 0.5
+		stepItem.NValueInterval = 0.01
+		stepItem.NValueFmtString = "%.2f"
+		stepItem.NumberCallback = func(nValue float32) {
+			TheOptions.SpeedTrainerStep = f64(nValue)
+		}
+		op.Menu.AddItems(stepItem)
+		op.OnMatchItemsToOption(func() {
+			op.Menu.SetItemNvalue(stepItem.Id, false, f32(TheOptions.SpeedTrainerStep))
+		})
+
+		passesItem := NewMenuItem()
+		passesItem.Name = "Clean Passes Needed"
+		passesItem.Type = MenuItemNumber
+		passesItem.NValue = f32(TheOptions.SpeedTrainerPasses)
+		passesItem.NValueMin = 1
+		passesItem.NValueMax = SpeedTrainerPassesMax
+		passesItem.NValueInterval = 1
+		passesItem.NValueFmtString = "%1.f"
+		passesItem.NumberCallback = func(nValue float32) {
+			TheOptions.SpeedTrainerPasses = int(nValue)
+		}
+		op.Menu.AddItems(passesItem)
+		op.OnMatchItemsToOption(func() {
+			op.Menu.SetItemNvalue(passesItem.Id, false, f32(TheOptions.SpeedTrainerPasses))
+		})
+	}
+
 	// ================================
 	// add rating options
 	// ================================
@@ -650,6 +948,12 @@ func NewOptionsControlsScreen() *BaseOptionsScreen {
 				item.NameMinWidth = 455
 			case SetBookMarkKey, JumpToBookMarkKey:
 				item.NameMinWidth = 455
+			case PrevBookMarkKey, NextBookMarkKey, RenameBookMarkKey:
+				item.NameMinWidth = 455
+			case LoopStartKey, LoopEndKey, ClearLoopKey:
+				item.NameMinWidth = 455
+			case NextTroubleSpotKey, ReviewModeKey:
+				item.NameMinWidth = 455
 			case AudioSpeedUpKey, AudioSpeedDownKey:
 				item.NameMinWidth = 290
 			case ZoomInKey, ZoomOutKey:
@@ -662,7 +966,10 @@ func NewOptionsControlsScreen() *BaseOptionsScreen {
 				NoteScrollDownKey,
 				AudioSpeedDownKey,
 				AudioOffsetDownKey,
-				JumpToBookMarkKey,
+				RenameBookMarkKey,
+				ClearLoopKey,
+				NextTroubleSpotKey,
+				ReviewModeKey,
 				ZoomInKey:
 
 				item.BottomMargin += extraBottomMargin
@@ -0,0 +1,14 @@
+//go:build !fnfopus
+
+package fnf
+
+import (
+	"fmt"
+)
+
+// true if app is built with opus decoding
+const OpusSupported = false
+
+func decodeOpus(rawFile []byte) (AudioDecoder, error) {
+	return nil, fmt.Errorf("opus is not supported in this build (build with fnfopus tag)")
+}
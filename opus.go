@@ -0,0 +1,59 @@
+//go:build fnfopus
+
+package fnf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+// true if app is built with opus decoding
+const OpusSupported = true
+
+// Opus is always decoded at 48000Hz.
+const opusSampleRate = 48000
+
+// Returns channel count written in the OpusHead packet.
+func opusChannelCount(rawFile []byte) (int, error) {
+	head := bytes.Index(rawFile, []byte("OpusHead"))
+	if head < 0 || head+9 >= len(rawFile) {
+		return 0, fmt.Errorf("failed to find opus header")
+	}
+	return int(rawFile[head+9]), nil
+}
+
+func decodeOpus(rawFile []byte) (AudioDecoder, error) {
+	channels, err := opusChannelCount(rawFile)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := opus.NewStream(bytes.NewReader(rawFile))
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var samples []int16
+
+	// 120ms is the longest opus packet
+	buffer := make([]int16, opusSampleRate*120/1000*channels)
+
+	for {
+		n, err := stream.Read(buffer)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		samples = append(samples, buffer[:n*channels]...)
+	}
+
+	return newPcmDecoder(samples, channels, opusSampleRate)
+}
@@ -0,0 +1,196 @@
+package fnf
+
+import (
+	"math"
+	"time"
+)
+
+// score for each rating, misses are worth 0
+var RatingScores = [HitRatingSize]int{
+	HitRatingBad:  100,
+	HitRatingGood: 200,
+	HitRatingSick: 350,
+}
+
+type PlayStatsHit struct {
+	Time   time.Duration // note time
+	Diff   time.Duration // hit time - note time
+	Rating FnfHitRating
+}
+
+type PlayStats struct {
+	// notes that were either hit or missed
+	JudgedCount int
+
+	RatingCounts [HitRatingSize]int
+	MissCount    int
+
+	MaxCombo int
+
+	Score int
+
+	// 0 to 1
+	Accuracy float64
+
+	MeanOffset   time.Duration
+	StdDevOffset time.Duration
+
+	Hits   []PlayStatsHit
+	Misses []time.Duration
+}
+
+// Calculates stats of player from note events.
+//
+// Only notes that starts in [from, to) are counted.
+// Notes that were neither hit nor missed (i.e. not played yet) are ignored.
+func CalculatePlayStats(
+	song FnfSong,
+	noteEvents [][]NoteEvent,
+	player FnfPlayerNo,
+	from, to time.Duration,
+) PlayStats {
+	var stats PlayStats
+
+	combo := 0
+
+	var diffSum float64
+
+	for i, note := range song.Notes {
+		if note.Player != player {
+			continue
+		}
+		if note.StartsAt < from || note.StartsAt >= to {
+			continue
+		}
+		if i >= len(noteEvents) {
+			break
+		}
+
+		firstHit, missed := judgeNote(noteEvents[i])
+
+		if !firstHit.IsNone() {
+			diff := firstHit.Time - note.StartsAt
+			rating := GetHitRating(note.StartsAt, firstHit.Time)
+
+			stats.Hits = append(stats.Hits, PlayStatsHit{
+				Time:   note.StartsAt,
+				Diff:   diff,
+				Rating: rating,
+			})
+
+			stats.RatingCounts[rating]++
+			stats.Score += RatingScores[rating]
+			stats.JudgedCount++
+
+			diffSum += f64(diff)
+
+			combo++
+			stats.MaxCombo = max(stats.MaxCombo, combo)
+		} else if missed {
+			stats.Misses = append(stats.Misses, note.StartsAt)
+
+			stats.MissCount++
+			stats.JudgedCount++
+
+			combo = 0
+		}
+	}
+
+	if stats.JudgedCount > 0 {
+		stats.Accuracy = f64(stats.Score) / f64(stats.JudgedCount*RatingScores[HitRatingSick])
+	}
+
+	if len(stats.Hits) > 0 {
+		mean := diffSum / f64(len(stats.Hits))
+
+		var variance float64
+		for _, hit := range stats.Hits {
+			d := f64(hit.Diff) - mean
+			variance += d * d
+		}
+		variance /= f64(len(stats.Hits))
+
+		stats.MeanOffset = time.Duration(mean)
+		stats.StdDevOffset = time.Duration(math.Sqrt(variance))
+	}
+
+	return stats
+}
+
+// Returns first hit event of a note (if there is one) and whether note was missed.
+func judgeNote(events []NoteEvent) (firstHit NoteEvent, missed bool) {
+	for _, e := range events {
+		if e.IsFirstHit() && firstHit.IsNone() {
+			firstHit = e
+		}
+		if e.IsMiss() {
+			missed = true
+		}
+	}
+
+	return firstHit, missed
+}
+
+// Calculates accuracy of each section.
+//
+// Section i is [bounds[i], bounds[i+1]), so there are len(bounds)-1 sections.
+// Sections with no judged notes get a negative accuracy.
+//
+// Unlike calling CalculatePlayStats for each section,
+// this goes through notes only once.
+func CalculateSectionAccuracies(
+	song FnfSong,
+	noteEvents [][]NoteEvent,
+	player FnfPlayerNo,
+	bounds []time.Duration,
+) []float64 {
+	if len(bounds) < 2 {
+		return nil
+	}
+
+	scores := make([]int, len(bounds)-1)
+	judged := make([]int, len(bounds)-1)
+
+	section := 0
+
+	for i, note := range song.Notes {
+		if note.Player != player {
+			continue
+		}
+		if i >= len(noteEvents) {
+			break
+		}
+
+		// notes are sorted so we only have to move forward
+		for section < len(scores) && note.StartsAt >= bounds[section+1] {
+			section++
+		}
+		if section >= len(scores) {
+			break
+		}
+		if note.StartsAt < bounds[section] {
+			continue
+		}
+
+		firstHit, missed := judgeNote(noteEvents[i])
+
+		if !firstHit.IsNone() {
+			scores[section] += RatingScores[GetHitRating(note.StartsAt, firstHit.Time)]
+			judged[section]++
+		} else if missed {
+			judged[section]++
+		}
+	}
+
+	accuracies := make([]float64, len(scores))
+
+	for i := range accuracies {
+		if judged[i] > 0 {
+			accuracies[i] = f64(scores[i]) / f64(judged[i]*RatingScores[HitRatingSick])
+		} else {
+			accuracies[i] = -1
+		}
+	}
+
+	return accuracies
+}
@@ -0,0 +1,165 @@
+package fnf
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const ms = time.Millisecond
+
+func testHit(index int, at time.Duration) NoteEvent {
+	e := NoteEvent{Time: at, Index: index}
+	e.SetFirstHit()
+	return e
+}
+
+func testMiss(index int, at time.Duration) NoteEvent {
+	e := NoteEvent{Time: at, Index: index}
+	e.SetMiss()
+	return e
+}
+
+// Makes a song with a note for given player every 500ms.
+func testSong(players ...FnfPlayerNo) FnfSong {
+	var song FnfSong
+
+	for i, player := range players {
+		song.Notes = append(song.Notes, FnfNote{
+			Player:   player,
+			StartsAt: time.Duration(i) * 500 * ms,
+			Index:    i,
+		})
+	}
+
+	return song
+}
+
+func TestPlayStatsRatingsAndAccuracy(t *testing.T) {
+	TheOptions.HitWindows = DefaultOptions.HitWindows
+
+	song := testSong(0, 0, 0, 0)
+
+	// sick, good, bad and a miss
+	events := [][]NoteEvent{
+		{testHit(0, 0)},
+		{testHit(1, 500*ms+60*ms)},
+		{testHit(2, 1000*ms+100*ms)},
+		{testMiss(3, 1500*ms+200*ms)},
+	}
+
+	stats := CalculatePlayStats(song, events, 0, 0, time.Hour)
+
+	wantRatings := [HitRatingSize]int{HitRatingSick: 1, HitRatingGood: 1, HitRatingBad: 1}
+
+	if stats.RatingCounts != wantRatings {
+		t.Errorf("RatingCounts = %v, want %v", stats.RatingCounts, wantRatings)
+	}
+	if stats.JudgedCount != 4 || stats.MissCount != 1 {
+		t.Errorf("judged %v notes with %v misses, want 4 and 1", stats.JudgedCount, stats.MissCount)
+	}
+
+	// misses are worth nothing but still count against accuracy
+	wantScore := RatingScores[HitRatingSick] + RatingScores[HitRatingGood] + RatingScores[HitRatingBad]
+	wantAccuracy := f64(wantScore) / f64(4*RatingScores[HitRatingSick])
+
+	if stats.Score != wantScore {
+		t.Errorf("Score = %v, want %v", stats.Score, wantScore)
+	}
+	if math.Abs(stats.Accuracy-wantAccuracy) > 0.0001 {
+		t.Errorf("Accuracy = %v, want %v", stats.Accuracy, wantAccuracy)
+	}
+}
+
+func TestPlayStatsMaxCombo(t *testing.T) {
+	TheOptions.HitWindows = DefaultOptions.HitWindows
+
+	song := testSong(0, 0, 0, 0, 0, 0)
+
+	events := [][]NoteEvent{
+		{testHit(0, 0)},
+		{testHit(1, 500*ms)},
+		{testMiss(2, 1000*ms+200*ms)},
+		{testHit(3, 1500*ms)},
+		{testHit(4, 2000*ms)},
+		{testHit(5, 2500*ms)},
+	}
+
+	stats := CalculatePlayStats(song, events, 0, 0, time.Hour)
+
+	if stats.MaxCombo != 3 {
+		t.Errorf("MaxCombo = %v, want 3", stats.MaxCombo)
+	}
+}
+
+func TestPlayStatsOnlyCountsPlayedNotesInRange(t *testing.T) {
+	TheOptions.HitWindows = DefaultOptions.HitWindows
+
+	// second note is opponent's and the last one isn't played yet
+	song := testSong(0, 1, 0, 0, 0)
+
+	events := [][]NoteEvent{
+		{testMiss(0, 200*ms)},
+		{testHit(1, 500*ms)},
+		{testHit(2, 1000*ms)},
+		{testMiss(3, 1500*ms+200*ms)},
+		nil,
+	}
+
+	stats := CalculatePlayStats(song, events, 0, 500*ms, 1500*ms)
+
+	if stats.JudgedCount != 1 || stats.RatingCounts[HitRatingSick] != 1 {
+		t.Errorf("judged %v notes (%v), want only the note at 1s",
+			stats.JudgedCount, stats.RatingCounts)
+	}
+
+	stats = CalculatePlayStats(song, events, 0, 0, time.Hour)
+
+	if stats.JudgedCount != 3 {
+		t.Errorf("JudgedCount = %v, want 3", stats.JudgedCount)
+	}
+}
+
+func TestPlayStatsSustainDroppedAfterHit(t *testing.T) {
+	TheOptions.HitWindows = DefaultOptions.HitWindows
+
+	song := testSong(0)
+	song.Notes[0].Duration = time.Second
+
+	// dropping a sustain after hitting it isn't a missed note
+	events := [][]NoteEvent{
+		{testHit(0, 20*ms), testMiss(0, 300*ms)},
+	}
+
+	stats := CalculatePlayStats(song, events, 0, 0, time.Hour)
+
+	if stats.MissCount != 0 || stats.RatingCounts[HitRatingSick] != 1 {
+		t.Errorf("got %v misses and %v sicks, want a single sick",
+			stats.MissCount, stats.RatingCounts[HitRatingSick])
+	}
+	if stats.MeanOffset != 20*ms {
+		t.Errorf("MeanOffset = %v, want 20ms", stats.MeanOffset)
+	}
+}
+
+func TestPlayStatsOffsets(t *testing.T) {
+	TheOptions.HitWindows = DefaultOptions.HitWindows
+
+	song := testSong(0, 0, 0)
+
+	// misses don't have an offset
+	events := [][]NoteEvent{
+		{testHit(0, 10*ms)},
+		{testHit(1, 500*ms-30*ms)},
+		{testMiss(2, 1000*ms+200*ms)},
+	}
+
+	stats := CalculatePlayStats(song, events, 0, 0, time.Hour)
+
+	if stats.MeanOffset != -10*ms {
+		t.Errorf("MeanOffset = %v, want -10ms", stats.MeanOffset)
+	}
+	if AbsI(stats.StdDevOffset-20*ms) > time.Microsecond {
+		t.Errorf("StdDevOffset = %v, want 20ms", stats.StdDevOffset)
+	}
+}
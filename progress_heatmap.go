@@ -0,0 +1,125 @@
+package fnf
+
+import (
+	"time"
+)
+
+// Accuracy of each part of the song shown on the progress bar.
+//
+// It's only calculated again when something it depends on changes
+// since it has to go through every notes.
+type ProgressHeatmap struct {
+	Bounds     []time.Duration
+	Accuracies []float64
+
+	// set when note events change
+	dirty bool
+	// set when song changes
+	boundsDirty bool
+
+	// what we calculated the heatmap with
+	mode       HeatmapMode
+	player     FnfPlayerNo
+	hitWindows [HitRatingSize]time.Duration
+}
+
+// Marks accuracies to be calculated again next time heatmap is used.
+func (hm *ProgressHeatmap) MarkDirty() {
+	hm.dirty = true
+}
+
+// Marks the whole heatmap to be calculated again next time heatmap is used.
+func (hm *ProgressHeatmap) MarkSongChanged() {
+	hm.dirty = true
+	hm.boundsDirty = true
+}
+
+// Returns boundaries of the parts progress bar heatmap is split into.
+//
+// Part i is [bounds[i], bounds[i+1]).
+// Returns nil if heatmap is turned off.
+func (gs *GameScreen) HeatmapBounds() []time.Duration {
+	if !gs.IsSongLoaded {
+		return nil
+	}
+
+	// NOTE : NotesEndsAt isn't offset by OffsetNotesAndBpmChanges
+	songEnd := gs.Song.NotesEndsAt + GSC.PadStart
+
+	var bounds []time.Duration
+
+	switch TheOptions.ProgressBarHeatmap {
+	case HeatmapByMeasure:
+		pos := GSC.PadStart
+
+		for pos < songEnd {
+			bounds = append(bounds, pos)
+			pos += BeatsToTime(4, songBpmAt(gs.Song, pos))
+		}
+		bounds = append(bounds, pos)
+
+	case HeatmapByMustHit:
+		for i, section := range gs.Song.Sections {
+			if i > 0 && section.MustHit == gs.Song.Sections[i-1].MustHit {
+				continue
+			}
+			bounds = append(bounds, section.StartsAt)
+		}
+
+		if len(bounds) <= 0 {
+			return nil
+		}
+
+		// last section might end before the last note
+		bounds = append(bounds, max(bounds[len(bounds)-1], songEnd)+time.Millisecond)
+	}
+
+	return bounds
+}
+
+// Color for an accuracy in progress bar heatmap.
+func HeatmapColor(accuracy float64) FnfColor {
+	weak := FnfColor{0xFF, 0x4D, 0x4D, 0xFF}
+	middle := FnfColor{0xFF, 0xD7, 0x00, 0xFF}
+	strong := RatingColors[HitRatingGood]
+
+	// anything below 50% is just bad
+	t := Clamp((accuracy-0.5)/0.5, 0, 1)
+
+	if t < 0.5 {
+		return LerpRGB(weak, middle, t*2)
+	}
+	return LerpRGB(middle, strong, (t-0.5)*2)
+}
+
+// Returns up to date progress bar heatmap.
+func (gs *GameScreen) UpdatedHeatmap() *ProgressHeatmap {
+	hm := &gs.Heatmap
+
+	upToDate := !hm.dirty
+	upToDate = upToDate && hm.mode == TheOptions.ProgressBarHeatmap
+	upToDate = upToDate && hm.player == gs.mainPlayer()
+	// ratings depend on hit windows and replays change them
+	upToDate = upToDate && hm.hitWindows == TheOptions.HitWindows
+
+	if upToDate {
+		return hm
+	}
+
+	if hm.boundsDirty || hm.mode != TheOptions.ProgressBarHeatmap {
+		hm.Bounds = gs.HeatmapBounds()
+	}
+
+	hm.Accuracies = nil
+	if len(hm.Bounds) > 0 {
+		hm.Accuracies = CalculateSectionAccuracies(gs.Song, gs.NoteEvents, gs.mainPlayer(), hm.Bounds)
+	}
+
+	hm.dirty = false
+	hm.boundsDirty = false
+	hm.mode = TheOptions.ProgressBarHeatmap
+	hm.player = gs.mainPlayer()
+	hm.hitWindows = TheOptions.HitWindows
+
+	return hm
+}
@@ -0,0 +1,371 @@
+package fnf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+type ReplayEvent struct {
+	// audio position of the frame key was pressed or released
+	// and audio position of the frame before that
+	//
+	// NOTE : these are song time, they don't include GSC.PadStart
+	Time     time.Duration
+	PrevTime time.Duration
+
+	Direction NoteDir
+	Pressed   bool
+}
+
+type Replay struct {
+	SongName   string
+	Difficulty FnfDifficulty
+
+	// hash of the chart this replay was recorded on, from ChartHash()
+	ChartHash string
+
+	Date time.Time
+
+	// NOTE : events are already in audio time so these are just informational
+	// except we set the speed back when playing the replay
+	AudioSpeed  float64
+	AudioOffset time.Duration
+	// events are recorded with input offset applied
+	InputOffset time.Duration
+
+	// options that changes how notes are judged
+	HitWindows   [HitRatingSize]time.Duration
+	GhostTapping bool
+	OpponentMode bool
+
+	// sorted by time
+	Events []ReplayEvent
+}
+
+// Hashes what matters to gameplay (i.e. notes).
+//
+// Two charts with same notes will have the same hash
+// even if their json files are formatted differently.
+func ChartHash(song FnfSong) string {
+	hash := sha256.New()
+
+	for _, note := range song.Notes {
+		binary.Write(hash, binary.LittleEndian, int64(note.StartsAt))
+		binary.Write(hash, binary.LittleEndian, int64(note.Duration))
+		binary.Write(hash, binary.LittleEndian, int32(note.Direction))
+		binary.Write(hash, binary.LittleEndian, int32(note.Player))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Plays back the replay through the note loop.
+//
+// To make playback deterministic (i.e. not depend on frame rate),
+// note loop is stepped exactly through the frames where keys were pressed or released
+// when replay was recorded.
+type ReplayPlayer struct {
+	Replay Replay
+
+	eventIndex int
+
+	// where we stepped note loop up to
+	pos time.Duration
+
+	keys [NoteDirSize]bool
+}
+
+func NewReplayPlayer(replay Replay) *ReplayPlayer {
+	rp := new(ReplayPlayer)
+	rp.Replay = replay
+	return rp
+}
+
+func (rp *ReplayPlayer) Keys() [NoteDirSize]bool {
+	return rp.keys
+}
+
+// Called when audio position changed arbitrarily.
+//
+// Keys are released since game states are reset when position changes.
+func (rp *ReplayPlayer) Seek(audioPos time.Duration) {
+	rp.pos = audioPos
+	rp.keys = [NoteDirSize]bool{}
+
+	rp.eventIndex = 0
+
+	for rp.eventIndex < len(rp.Replay.Events) &&
+		rp.Replay.Events[rp.eventIndex].PrevTime+GSC.PadStart < audioPos {
+		rp.eventIndex++
+	}
+}
+
+// Steps note loop up to audioPos.
+//
+// It may step a bit past audioPos (at most a frame of the recording)
+// so that it doesn't have to split frame where key changed.
+func (rp *ReplayPlayer) Update(
+	song FnfSong,
+	pState PlayerState,
+	player FnfPlayerNo,
+	audioPos time.Duration,
+	audioEnd time.Duration,
+	isPlayingAudio bool,
+	hitWindow time.Duration,
+	noteIndexStart int,
+) (PlayerState, []NoteEvent, []Mispress) {
+	var noteEvents []NoteEvent
+	var mispresses []Mispress
+
+	step := func(wasKeys, keys [NoteDirSize]bool, from, to time.Duration) {
+		var events []NoteEvent
+
+		pState, events = UpdateNotesAndStatesForHuman(
+			song,
+			pState,
+			player,
+			wasKeys,
+			keys,
+			from,
+			to,
+			audioEnd,
+			isPlayingAudio,
+			hitWindow,
+			noteIndexStart,
+		)
+
+		noteEvents = append(noteEvents, events...)
+
+		if !rp.Replay.GhostTapping {
+			for dir := range NoteDirSize {
+				if pState.IsHoldingBadKey[dir] && pState.IsKeyJustPressed[dir] {
+					mispresses = append(mispresses, Mispress{
+						Player: player, Direction: dir, Time: to,
+					})
+				}
+			}
+		}
+	}
+
+	if !isPlayingAudio {
+		// note loop only cleans up at the end of the song when audio isn't playing
+		step(rp.keys, rp.keys, audioPos, audioPos)
+		return pState, noteEvents, mispresses
+	}
+
+	events := rp.Replay.Events
+
+	for rp.eventIndex < len(events) && events[rp.eventIndex].PrevTime+GSC.PadStart <= audioPos {
+		prevTime := events[rp.eventIndex].PrevTime + GSC.PadStart
+		eventTime := events[rp.eventIndex].Time + GSC.PadStart
+
+		// step up to the frame before keys changed
+		if rp.pos < prevTime {
+			step(rp.keys, rp.keys, rp.pos, prevTime)
+		}
+
+		// events from the same frame are applied together
+		wasKeys := rp.keys
+
+		for rp.eventIndex < len(events) && events[rp.eventIndex].Time+GSC.PadStart == eventTime {
+			e := events[rp.eventIndex]
+			rp.keys[e.Direction] = e.Pressed
+			rp.eventIndex++
+		}
+
+		step(wasKeys, rp.keys, prevTime, eventTime)
+
+		rp.pos = eventTime
+	}
+
+	if rp.pos < audioPos {
+		step(rp.keys, rp.keys, rp.pos, audioPos)
+		rp.pos = audioPos
+	}
+
+	return pState, noteEvents, mispresses
+}
+
+// Hit window that was used when replay was recorded.
+func (r Replay) HitWindow() time.Duration {
+	return max(
+		r.HitWindows[HitRatingBad],
+		r.HitWindows[HitRatingGood],
+		r.HitWindows[HitRatingSick],
+	) * 2
+}
+
+// Plays the whole replay without audio and returns note events like GameScreen.NoteEvents.
+//
+// song should be offset by GSC.PadStart like the one GameScreen uses.
+func SimulateReplay(song FnfSong, replay Replay) [][]NoteEvent {
+	song = song.Copy()
+
+	for i := range song.Notes {
+		song.Notes[i].IsHit = false
+		song.Notes[i].HoldReleaseAt = 0
+	}
+
+	noteEvents := make([][]NoteEvent, len(song.Notes))
+
+	if len(song.Notes) <= 0 {
+		return noteEvents
+	}
+
+	rp := NewReplayPlayer(replay)
+
+	player := mainPlayer(replay.OpponentMode)
+	hitWindow := replay.HitWindow()
+
+	var pState PlayerState
+	noteIndexStart := 0
+
+	var end time.Duration
+	for _, note := range song.Notes {
+		end = max(end, note.End()+hitWindow)
+	}
+
+	// misses are only checked at each step
+	// so this has to be much smaller than the hit window
+	const stepSize = time.Millisecond * 5
+
+	for pos := time.Duration(0); pos < end+stepSize; pos += stepSize {
+		var events []NoteEvent
+
+		pState, events, _ = rp.Update(
+			song, pState, player, pos, end, true, hitWindow, noteIndexStart)
+
+		noteIndexStart = CalculateNewNoteIndexStart(song, pos, hitWindow, noteIndexStart)
+
+		for _, e := range events {
+			noteEvents[e.Index] = append(noteEvents[e.Index], e)
+		}
+	}
+
+	return noteEvents
+}
+
+func (gs *GameScreen) IsReplaying() bool {
+	return gs.ReplayPlayer != nil
+}
+
+func (gs *GameScreen) StartReplay(replay Replay) error {
+	if replay.ChartHash != gs.ChartHashes[gs.SelectedDifficulty] {
+		return fmt.Errorf("replay was recorded on a different chart")
+	}
+
+	if gs.IsReplaying() {
+		gs.StopReplay()
+	}
+
+	gs.SetBotPlay(false)
+	gs.SpeedTrainer.Enabled = false
+	gs.ClearLoop()
+	gs.ClearRewind()
+
+	gs.opponentModeBeforeReplay = gs.OpponentMode
+	gs.audioSpeedBeforeReplay = gs.AudioSpeed()
+
+	gs.OpponentMode = replay.OpponentMode
+
+	// replay has to be judged the same way it was recorded
+	gs.hitWindowsBeforeReplay = TheOptions.HitWindows
+	TheOptions.HitWindows = replay.HitWindows
+
+	gs.ReplayPlayer = NewReplayPlayer(replay)
+
+	gs.SetAudioSpeed(replay.AudioSpeed)
+	gs.SetAudioPositionNoOffset(0)
+	gs.ResetGameStates()
+
+	gs.PlayAudio()
+
+	return nil
+}
+
+func (gs *GameScreen) StopReplay() {
+	if !gs.IsReplaying() {
+		return
+	}
+
+	TheOptions.HitWindows = gs.hitWindowsBeforeReplay
+	gs.OpponentMode = gs.opponentModeBeforeReplay
+
+	gs.ReplayPlayer = nil
+
+	gs.SetAudioSpeed(gs.audioSpeedBeforeReplay)
+	gs.ResetGameStates()
+}
+
+// Removes replay events that happened after given audio position.
+func (gs *GameScreen) truncateReplayEvents(at time.Duration) {
+	at -= GSC.PadStart
+
+	var keys [NoteDirSize]bool
+
+	for i, e := range gs.replayEvents {
+		if e.Time >= at {
+			gs.replayEvents = gs.replayEvents[:i]
+			break
+		}
+		keys[e.Direction] = e.Pressed
+	}
+
+	// keys are released when game states are reset
+	for dir := range NoteDirSize {
+		if keys[dir] {
+			gs.replayEvents = append(gs.replayEvents, ReplayEvent{
+				Time:      at,
+				PrevTime:  at,
+				Direction: dir,
+				Pressed:   false,
+			})
+		}
+	}
+}
+
+// Returns file name of the saved replay, empty string if it wasn't saved.
+func (gs *GameScreen) saveReplay() string {
+	if len(gs.replayEvents) <= 0 {
+		return ""
+	}
+
+	replay := Replay{
+		SongName:   gs.Songs[gs.SelectedDifficulty].SongName,
+		Difficulty: gs.SelectedDifficulty,
+
+		ChartHash: gs.ChartHashes[gs.SelectedDifficulty],
+
+		Date: time.Now(),
+
+		AudioSpeed:  gs.AudioSpeed(),
+		AudioOffset: TheOptions.AudioOffset,
+		InputOffset: TheOptions.InputOffset,
+
+		HitWindows:   TheOptions.HitWindows,
+		GhostTapping: TheOptions.GhostTapping,
+		OpponentMode: gs.OpponentMode,
+
+		Events: slices.Clone(gs.replayEvents),
+	}
+
+	path, err := SaveReplay(replay)
+	if err != nil {
+		ErrorLogger.Printf("failed to save replay: %v", err)
+		DisplayAlert("failed to save replay")
+		return ""
+	}
+
+	FnfLogger.Printf("saved replay to %s", path)
+
+	return filepath.Base(path)
+}
+
+func (gs *GameScreen) DrawReplayIcon() {
+	gs.drawStatusText("Replay", 190)
+}
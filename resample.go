@@ -0,0 +1,164 @@
+package fnf
+
+import (
+	"math"
+)
+
+// Windowed sinc interpolation used when audio is played at varying speed.
+//
+// Kernel is precomputed for resamplePhases fractional positions
+// between two samples.
+//
+// When audio is sped up (or resampled to a lower rate), cutoff of the kernel
+// is lowered to the new nyquist frequency so that it doesn't alias.
+// Kernel gets wider by the same amount to keep the same quality.
+
+const (
+	resampleTaps   = 8
+	resamplePhases = 256
+)
+
+type resampleFilter struct {
+	taps   int
+	kernel [resamplePhases + 1][]float32
+}
+
+// Makes a filter with cutoff at given fraction of the nyquist frequency (0 < cutoff <= 1).
+func newResampleFilter(cutoff float64) *resampleFilter {
+	taps := int(math.Ceil(resampleTaps / cutoff))
+	taps += taps % 2
+
+	half := taps / 2
+
+	sinc := func(x float64) float64 {
+		if math.Abs(x) < 1e-9 {
+			return 1
+		}
+		return math.Sin(math.Pi*x) / (math.Pi * x)
+	}
+
+	// blackman window that spans the kernel
+	window := func(x float64) float64 {
+		t := (x + f64(half)) / f64(taps)
+		return 0.42 - 0.5*math.Cos(2*math.Pi*t) + 0.08*math.Cos(4*math.Pi*t)
+	}
+
+	f := new(resampleFilter)
+	f.taps = taps
+
+	kernel := make([]float64, taps)
+
+	for phase := range resamplePhases + 1 {
+		frac := f64(phase) / resamplePhases
+
+		var sum float64
+
+		for tap := range taps {
+			// distance from the tap to the position we want
+			x := f64(tap-half+1) - frac
+			kernel[tap] = sinc(x*cutoff) * window(x)
+			sum += kernel[tap]
+		}
+
+		// normalize so that it doesn't change the volume
+		f.kernel[phase] = make([]float32, taps)
+		for tap := range taps {
+			f.kernel[phase][tap] = f32(kernel[tap] / sum)
+		}
+	}
+
+	return f
+}
+
+// filter used when audio is slowed down or played at normal speed
+var defaultResampleFilter = newResampleFilter(1)
+
+// Returns filter for the current speed of the stream.
+func (vs *VaryingSpeedStream) resampleFilter() *resampleFilter {
+	if vs.speed <= 1 {
+		return defaultResampleFilter
+	}
+
+	if vs.filter == nil || vs.filterSpeed != vs.speed {
+		vs.filter = newResampleFilter(1 / vs.speed)
+		vs.filterSpeed = vs.speed
+	}
+
+	return vs.filter
+}
+
+func readStreamSample(vs *VaryingSpeedStream, sample int64) (float32, float32) {
+	b := vs.readSrc(sample * BytesPerSample)
+
+	l := int16(uint16(b[0]) | uint16(b[1])<<8)
+	r := int16(uint16(b[2]) | uint16(b[3])<<8)
+
+	return f32(l), f32(r)
+}
+
+// Returns stereo sample at sample + frac (0 <= frac < 1).
+func interpolateStreamSample(vs *VaryingSpeedStream, sample int64, frac float64) (int16, int16) {
+	filter := vs.resampleFilter()
+	half := filter.taps / 2
+
+	kernel := filter.kernel[int(frac*resamplePhases+0.5)]
+
+	var l, r float32
+
+	for tap, k := range kernel {
+		sl, sr := readStreamSample(vs, sample+int64(tap-half+1))
+		l += sl * k
+		r += sr * k
+	}
+
+	return int16(Clamp(l, math.MinInt16, math.MaxInt16)),
+		int16(Clamp(r, math.MinInt16, math.MaxInt16))
+}
+
+// Resamples interleaved stereo samples from one sample rate to another.
+//
+// Used for decoded files whose sample rate isn't SampleRate.
+func resampleStereo(samples []int16, from, to int) []int16 {
+	filter := defaultResampleFilter
+	if from > to {
+		filter = newResampleFilter(f64(to) / f64(from))
+	}
+
+	half := filter.taps / 2
+
+	frameCount := int64(len(samples) / 2)
+	outCount := frameCount * int64(to) / int64(from)
+
+	out := make([]int16, outCount*2)
+
+	sampleAt := func(i int64) (float32, float32) {
+		if i < 0 || i >= frameCount {
+			return 0, 0
+		}
+		return f32(samples[i*2+0]), f32(samples[i*2+1])
+	}
+
+	step := f64(from) / f64(to)
+
+	for i := range outCount {
+		pos := f64(i) * step
+
+		sample := int64(pos)
+		frac := pos - f64(sample)
+
+		kernel := filter.kernel[int(frac*resamplePhases+0.5)]
+
+		var l, r float32
+
+		for tap, k := range kernel {
+			sl, sr := sampleAt(sample + int64(tap-half+1))
+			l += sl * k
+			r += sr * k
+		}
+
+		out[i*2+0] = int16(Clamp(l, math.MinInt16, math.MaxInt16))
+		out[i*2+1] = int16(Clamp(r, math.MinInt16, math.MaxInt16))
+	}
+
+	return out
+}
@@ -0,0 +1,361 @@
+package fnf
+
+import (
+	"fmt"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type ResultScreen struct {
+	Stats PlayStats
+
+	SongName   string
+	Difficulty FnfDifficulty
+	AudioSpeed float64
+
+	// song duration, used for plot's x axis
+	Duration time.Duration
+
+	InputId InputGroupId
+}
+
+func NewResultScreen() *ResultScreen {
+	rs := new(ResultScreen)
+	rs.InputId = NewInputGroupId()
+	return rs
+}
+
+func (rs *ResultScreen) SetResult(
+	stats PlayStats,
+	songName string,
+	difficulty FnfDifficulty,
+	audioSpeed float64,
+	duration time.Duration,
+) {
+	rs.Stats = stats
+	rs.SongName = songName
+	rs.Difficulty = difficulty
+	rs.AudioSpeed = audioSpeed
+	rs.Duration = duration
+}
+
+func (rs *ResultScreen) PlotRect() rl.Rectangle {
+	return rl.Rectangle{
+		X: 60, Y: 380,
+		Width: SCREEN_WIDTH - 120, Height: 280,
+	}
+}
+
+func (rs *ResultScreen) plotMaxOffset() time.Duration {
+	return max(
+		TheOptions.HitWindows[HitRatingBad],
+		TheOptions.HitWindows[HitRatingGood],
+		TheOptions.HitWindows[HitRatingSick],
+		time.Millisecond, // just in case
+	)
+}
+
+func (rs *ResultScreen) timeToPlotX(t time.Duration) float32 {
+	rect := rs.PlotRect()
+	if rs.Duration <= 0 {
+		return rect.X
+	}
+	return rect.X + rect.Width*f32(f64(t)/f64(rs.Duration))
+}
+
+func (rs *ResultScreen) plotXToTime(x float32) time.Duration {
+	rect := rs.PlotRect()
+	t := f64(x-rect.X) / f64(rect.Width)
+	t = Clamp(t, 0, 1)
+	return time.Duration(t * f64(rs.Duration))
+}
+
+func (rs *ResultScreen) offsetToPlotY(offset time.Duration) float32 {
+	rect := rs.PlotRect()
+	maxOffset := rs.plotMaxOffset()
+
+	t := f32(offset) / f32(maxOffset)
+	t = Clamp(t, -1, 1)
+
+	// late hits go down, early hits go up
+	return rect.Y + rect.Height*0.5 + t*rect.Height*0.5
+}
+
+func (rs *ResultScreen) IsPlotHovering() bool {
+	return rl.CheckCollisionPointRec(MouseV(), rs.PlotRect())
+}
+
+func (rs *ResultScreen) Update(deltaTime time.Duration) {
+	if AreKeysPressed(rs.InputId, TheKM[EscapeKey]) {
+		TheGameScreen.LeaveResult()
+
+		ShowTransition(BlackPixel, func() {
+			SetNextScreen(TheSelectScreen)
+			HideTransition()
+		})
+		return
+	}
+
+	// go back to where we were (end of the song)
+	if AreKeysPressed(rs.InputId, TheKM[SelectKey]) {
+		TheGameScreen.ReturnFromResult(TheGameScreen.AudioPosition())
+		SetNextScreen(TheGameScreen)
+		return
+	}
+
+	// review the song from the start
+	if AreKeysPressed(rs.InputId, TheKM[ReviewModeKey]) {
+		TheGameScreen.ReturnFromResult(0)
+		TheGameScreen.SetReviewMode(true)
+		SetNextScreen(TheGameScreen)
+		return
+	}
+
+	if rs.IsPlotHovering() && IsMouseButtonPressed(rs.InputId, rl.MouseButtonLeft) {
+		// jump a bit before the clicked time so that user can get ready
+		at := rs.plotXToTime(MouseX()) - time.Millisecond*500
+		at = max(at, 0)
+
+		TheGameScreen.ReturnFromResult(at)
+		SetNextScreen(TheGameScreen)
+	}
+}
+
+func (rs *ResultScreen) Draw() {
+	DrawPatternBackground(MenuScreenBg, 0, 0, ToRlColor(FnfColor{255, 255, 255, 255}))
+
+	black := ToRlColor(FnfColor{0, 0, 0, 255})
+	white := ToRlColor(FnfColor{255, 255, 255, 255})
+
+	// ============================================
+	// draw title
+	// ============================================
+	{
+		const fontSize = 60
+
+		title := rs.SongName
+		if title == "" {
+			title = "Results"
+		}
+
+		DrawTextOutlined(
+			SdfFontBold, title, rl.Vector2{60, 30}, fontSize, 0,
+			white, black, 4,
+		)
+
+		subTitle := fmt.Sprintf("%s  x%.1f", DifficultyStrs[rs.Difficulty], rs.AudioSpeed)
+
+		DrawTextOutlined(
+			SdfFontBold, subTitle, rl.Vector2{60, 30 + fontSize}, fontSize*0.6, 0,
+			white, black, 4,
+		)
+	}
+
+	// ============================================
+	// draw stats
+	// ============================================
+	{
+		const textSize = 30
+
+		stats := rs.Stats
+
+		labelPos := rl.Vector2{60, 150}
+
+		rl.SetTextLineSpacing(textSize)
+
+		labels := "Score:\n" +
+			"Accuracy:\n" +
+			"Max Combo:\n" +
+			"Mean:\n" +
+			"Std Dev:"
+
+		labelSize := MeasureText(FontClear, labels, textSize, 0)
+
+		DrawText(FontClear, labels, labelPos, textSize, 0, black)
+
+		numbers := fmt.Sprintf(
+			"%d\n"+
+				"%.2f%%\n"+
+				"%d\n"+
+				"%+.1fms\n"+
+				"%.1fms",
+			stats.Score,
+			stats.Accuracy*100,
+			stats.MaxCombo,
+			f64(stats.MeanOffset)/f64(time.Millisecond),
+			f64(stats.StdDevOffset)/f64(time.Millisecond),
+		)
+
+		DrawText(
+			FontClear, numbers,
+			rl.Vector2{labelPos.X + labelSize.X + 12, labelPos.Y},
+			textSize, 0, black,
+		)
+
+		// draw rating counts
+		countPos := rl.Vector2{SCREEN_WIDTH * 0.5, 150}
+
+		DrawText(
+			FontClear, fmt.Sprintf("Miss: %d", stats.MissCount),
+			countPos, textSize, 0, ToRlColor(FnfColor{255, 0, 0, 255}),
+		)
+
+		for r := HitRatingSize - 1; r >= 0; r-- {
+			countPos.Y += textSize
+
+			DrawText(
+				FontClear, fmt.Sprintf("%s: %d", RatingStrs[r], stats.RatingCounts[r]),
+				countPos, textSize, 0, black,
+			)
+		}
+	}
+
+	// ============================================
+	// draw timing graph
+	// ============================================
+	{
+		rect := rs.PlotRect()
+
+		rl.DrawRectangleRec(rect, ToRlColor(FnfColor{0, 0, 0, 200}))
+
+		// draw hit window bands
+		for r := FnfHitRating(0); r < HitRatingSize; r++ {
+			window := TheOptions.HitWindows[r]
+
+			y0 := rs.offsetToPlotY(-window)
+			y1 := rs.offsetToPlotY(window)
+
+			col := RatingColors[r]
+			col.A = 40
+
+			rl.DrawRectangleRec(rl.Rectangle{
+				X: rect.X, Y: y0, Width: rect.Width, Height: y1 - y0,
+			}, ToRlColor(col))
+		}
+
+		// draw center line
+		centerY := rs.offsetToPlotY(0)
+		rl.DrawLineEx(
+			rl.Vector2{rect.X, centerY}, rl.Vector2{rect.X + rect.Width, centerY},
+			2, ToRlColor(FnfColor{255, 255, 255, 150}),
+		)
+
+		// draw misses
+		for _, miss := range rs.Stats.Misses {
+			x := rs.timeToPlotX(miss)
+			rl.DrawLineEx(
+				rl.Vector2{x, rect.Y}, rl.Vector2{x, rect.Y + rect.Height},
+				2, ToRlColor(FnfColor{255, 0, 0, 150}),
+			)
+		}
+
+		// draw hits
+		for _, hit := range rs.Stats.Hits {
+			x := rs.timeToPlotX(hit.Time)
+			y := rs.offsetToPlotY(hit.Diff)
+
+			rl.DrawCircleV(rl.Vector2{x, y}, 3, ToRlColor(RatingColors[hit.Rating]))
+		}
+
+		// draw axis labels
+		{
+			const fontSize = 20
+
+			early := fmt.Sprintf("-%dms (early)", rs.plotMaxOffset().Milliseconds())
+			late := fmt.Sprintf("+%dms (late)", rs.plotMaxOffset().Milliseconds())
+
+			DrawText(FontClear, early, rl.Vector2{rect.X + 5, rect.Y + 3}, fontSize, 0, white)
+			DrawText(
+				FontClear, late, rl.Vector2{rect.X + 5, rect.Y + rect.Height - fontSize - 3},
+				fontSize, 0, white,
+			)
+		}
+
+		// draw cursor
+		if rs.IsPlotHovering() {
+			x := MouseX()
+
+			rl.DrawLineEx(
+				rl.Vector2{x, rect.Y}, rl.Vector2{x, rect.Y + rect.Height},
+				2, white,
+			)
+
+			const fontSize = 22
+
+			t := rs.plotXToTime(x)
+			timeStr := fmt.Sprintf("%d:%02d", int(t.Minutes()), int(t.Seconds())%60)
+
+			textSize := MeasureText(FontBold, timeStr, fontSize, 0)
+
+			DrawTextOutlined(
+				FontBold, timeStr,
+				rl.Vector2{x - textSize.X*0.5, rect.Y - textSize.Y - 4},
+				fontSize, 0, white, black, 3,
+			)
+		}
+
+		rl.DrawRectangleLinesEx(rect, 2, white)
+	}
+
+	// ============================================
+	// draw help message
+	// ============================================
+	{
+		const fontSize = 30
+		const margin = 15
+
+		factory := NewRichTextFactory(SCREEN_WIDTH)
+		factory.LineBreakRule = LineBreakNever
+
+		styleBlack := RichTextStyle{
+			FontSize:    fontSize,
+			Font:        SdfFontClear,
+			Fill:        FnfColor{0, 0, 0, 255},
+			Stroke:      FnfColor{255, 255, 255, 255},
+			StrokeWidth: 7,
+		}
+
+		styleRed := styleBlack
+		styleRed.Fill = FnfColor{0xFF, 0x00, 0x00, 0xFF}
+
+		factory.SetStyle(styleBlack)
+		factory.Print("click graph to jump back, ")
+
+		factory.SetStyle(styleRed)
+		factory.Print(GetKeyName(TheKM[SelectKey]))
+
+		factory.SetStyle(styleBlack)
+		factory.Print(" to go back, ")
+
+		factory.SetStyle(styleRed)
+		factory.Print(GetKeyName(TheKM[ReviewModeKey]))
+
+		factory.SetStyle(styleBlack)
+		factory.Print(" to review, ")
+
+		factory.SetStyle(styleRed)
+		factory.Print(GetKeyName(TheKM[EscapeKey]))
+
+		factory.SetStyle(styleBlack)
+		factory.Print(" to select song")
+
+		elements := factory.Elements(TextAlignLeft, 0, 0)
+		bound := ElementsBound(elements)
+
+		DrawTextElements(elements,
+			SCREEN_WIDTH-bound.Width-margin,
+			SCREEN_HEIGHT-bound.Height-margin,
+			FnfColor{255, 255, 255, 255},
+		)
+	}
+}
+
+func (rs *ResultScreen) BeforeScreenTransition() {
+}
+
+func (rs *ResultScreen) BeforeScreenEnd() {
+}
+
+func (rs *ResultScreen) Free() {
+}
@@ -0,0 +1,134 @@
+package fnf
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Review mode pauses the song and shows how each note was hit
+// so user can scroll back and see what went wrong.
+func (gs *GameScreen) SetReviewMode(review bool) {
+	gs.IsReviewing = review
+
+	if review {
+		gs.ClearTempPause()
+		gs.ClearRewind()
+		gs.PauseAudio()
+
+		// draw every notes including the ones that were hit
+		gs.positionChangedWhilePaused = true
+	}
+}
+
+func (gs *GameScreen) DrawReviewIcon() {
+	// draw it under bot play and replay text
+	gs.drawStatusText("Review", 260)
+}
+
+// Draws hit offsets and sustain drops of main player's notes.
+func (gs *GameScreen) DrawReviewInfo() {
+	const fontSize = 26
+
+	drawLabel := func(text string, x, y float32, fill FnfColor) {
+		size := MeasureText(SdfFontBold, text, fontSize, 0)
+
+		DrawTextOutlined(
+			SdfFontBold, text,
+			rl.Vector2{x - size.X*0.5, y - size.Y*0.5},
+			fontSize, 0,
+			ToRlColor(fill), ToRlColor(FnfColor{0, 0, 0, 255}), 4,
+		)
+	}
+
+	isOnScreen := func(y float32) bool {
+		return -GSC.NotesSize < y && y < SCREEN_HEIGHT+GSC.NotesSize
+	}
+
+	// hit offsets are drawn left of main player's lanes, at the height of the note
+	// so they don't cover other notes
+	offsetRight := gs.NoteX(gs.mainPlayer(), NoteDirLeft) - GSC.NotesSize*0.5 - 10
+
+	// notes are sorted by time so we only have to check the last label we drew
+	lastOffsetY := float32(0)
+	drewOffset := false
+
+	for _, note := range gs.Song.Notes {
+		if note.Player != gs.mainPlayer() {
+			continue
+		}
+
+		events := gs.NoteEvents[note.Index]
+		if len(events) <= 0 {
+			continue
+		}
+
+		x := gs.NoteX(note.Player, note.Direction)
+		y := gs.TimeToY(note.StartsAt)
+
+		// draw hit offset beside the note
+		// when notes are too close (chords, fast streams), only the first one gets it
+		if firstHit, _ := judgeNote(events); !firstHit.IsNone() && isOnScreen(y) &&
+			(!drewOffset || math.Abs(f64(y-lastOffsetY)) >= fontSize) {
+
+			diff := firstHit.Time - note.StartsAt
+			diffUnscaled := time.Duration(f64(diff) / f64(gs.AudioSpeed()))
+
+			rating := GetHitRating(note.StartsAt, firstHit.Time)
+
+			text := fmt.Sprintf("%+.1f", f64(diffUnscaled)/f64(time.Millisecond))
+			size := MeasureText(SdfFontBold, text, fontSize, 0)
+
+			drawLabel(text, offsetRight-size.X*0.5, y, RatingColors[rating])
+
+			lastOffsetY = y
+			drewOffset = true
+		}
+
+		// mark where user dropped the sustain
+		if note.IsSustain() {
+			firstEvent := events[0]
+
+			for i, m := range CalculateSustainMisses(note, events) {
+				// same as how we draw sustain misses, skip misses before first hit
+				if firstEvent.IsHit() && i == 0 && m.End-time.Millisecond <= firstEvent.Time {
+					continue
+				}
+				if m.End-m.Begin < time.Millisecond*10 {
+					continue
+				}
+
+				dropY := gs.TimeToY(m.Begin)
+				if !isOnScreen(dropY) {
+					continue
+				}
+
+				const lineH = 6
+
+				rl.DrawRectangleRec(
+					rl.Rectangle{
+						X: x - GSC.NotesSize*0.5, Y: dropY - lineH*0.5,
+						Width: GSC.NotesSize, Height: lineH,
+					},
+					ToRlColor(FnfColor{0xFF, 0x4D, 0x4D, 0xFF}),
+				)
+
+				drawLabel("drop", x, dropY+fontSize, FnfColor{0xFF, 0x4D, 0x4D, 0xFF})
+			}
+		}
+	}
+}
+
+// Tints note with color of the rating user got.
+func reviewTint(fill FnfColor, note FnfNote, events []NoteEvent) FnfColor {
+	firstHit, _ := judgeNote(events)
+	if firstHit.IsNone() {
+		return fill
+	}
+
+	rating := GetHitRating(note.StartsAt, firstHit.Time)
+
+	return LerpRGB(fill, RatingColors[rating], 0.7)
+}
@@ -12,11 +12,18 @@ import (
 const (
 	SettingsFilePath    = "fnf-practice-settings.json"
 	CollectionsFilePath = "fnf-practice-collections.json"
+	BookMarksFilePath   = "fnf-practice-bookmarks.json"
+	PlayHistoryFilePath = "fnf-practice-history.json"
+	ReplaysDirPath      = "fnf-practice-replays"
+	AudioCacheDirPath   = "fnf-practice-audio-cache"
+	SoundPacksDirPath   = "fnf-practice-sound-packs"
+
+	SongOverridesFilePath = "fnf-practice-song-overrides.json"
 )
 
 const (
 	SettingsJsonMajorVersion = 3
-	SettingsJsonMinorVersion = 1
+	SettingsJsonMinorVersion = 15
 )
 
 type SettingsJson struct {
@@ -29,7 +36,7 @@ type SettingsJson struct {
 
 const (
 	CollectionsJsonMajorVersion = 1
-	CollectionsJsonMinorVersion = 1
+	CollectionsJsonMinorVersion = 2
 )
 
 type CollectionsJson struct {
@@ -39,6 +46,57 @@ type CollectionsJson struct {
 	Collections []PathGroupCollection
 }
 
+const (
+	BookMarksJsonMajorVersion = 1
+	BookMarksJsonMinorVersion = 0
+)
+
+type BookMarksJson struct {
+	MajorVersion int
+	MinorVersion int
+
+	// key is from SongDataKey()
+	BookMarks map[string][]SongBookMark
+}
+
+const (
+	PlayHistoryJsonMajorVersion = 1
+	PlayHistoryJsonMinorVersion = 0
+)
+
+type PlayHistoryJson struct {
+	MajorVersion int
+	MinorVersion int
+
+	// key is from SongDataKey()
+	History map[string][]PlayRecord
+}
+
+const (
+	SongOverridesJsonMajorVersion = 1
+	SongOverridesJsonMinorVersion = 0
+)
+
+type SongOverridesJson struct {
+	MajorVersion int
+	MinorVersion int
+
+	// key is from SongDataKey() or SongGroupKey()
+	Overrides map[string]SongOverride
+}
+
+const (
+	ReplayJsonMajorVersion = 1
+	ReplayJsonMinorVersion = 0
+)
+
+type ReplayJson struct {
+	MajorVersion int
+	MinorVersion int
+
+	Replay Replay
+}
+
 func checkFileExists(path string) (bool, error) {
 	// check if file exists
 	info, err := os.Stat(path)
@@ -156,6 +214,21 @@ func LoadCollections() ([]PathGroupCollection, error) {
 			for pIndex := range collection.PathGroups {
 				collection.PathGroups[pIndex].id = NewFnfPathGroupId()
 			}
+
+			// move voice path of old collections to voice stems
+			for pIndex := range collection.PathGroups {
+				group := &collection.PathGroups[pIndex]
+
+				if group.VoicePath != "" {
+					if len(group.Voices) <= 0 {
+						group.Voices = []FnfVoiceStem{{
+							Path:   group.VoicePath,
+							Player: VoiceBothPlayers,
+						}}
+					}
+					group.VoicePath = ""
+				}
+			}
 		}
 
 		return jc.Collections, nil
@@ -225,6 +298,47 @@ func LoadSettings() error {
 		if js.Options.TargetFPS < 0 {
 			js.Options.TargetFPS = DefaultOptions.TargetFPS
 		}
+		if js.Options.AudioCacheSize < 0 || js.Options.AudioCacheSize > AudioCacheSizeMax {
+			js.Options.AudioCacheSize = DefaultOptions.AudioCacheSize
+		}
+		if js.Options.SoundPack == "" {
+			js.Options.SoundPack = DefaultOptions.SoundPack
+		}
+		if js.Options.MetronomeVolume < 0 || js.Options.MetronomeVolume > 1 {
+			js.Options.MetronomeVolume = DefaultOptions.MetronomeVolume
+		}
+		// settings saved before menu sound track was added have it at 0 volume
+		if js.MinorVersion < 15 {
+			js.Options.Mixer[MixerTrackMenu] = DefaultOptions.Mixer[MixerTrackMenu]
+		}
+		for track := MixerTrack(0); track < MixerTrackSize; track++ {
+			if js.Options.Mixer[track].Volume < 0 || js.Options.Mixer[track].Volume > 1 {
+				js.Options.Mixer[track].Volume = DefaultOptions.Mixer[track].Volume
+			}
+		}
+		if js.Options.InputOffset < 0 || js.Options.InputOffset > InputOffsetMax {
+			js.Options.InputOffset = DefaultOptions.InputOffset
+		}
+		if js.Options.LoopPreRoll < 0 || js.Options.LoopPreRoll > LoopPreRollMax {
+			js.Options.LoopPreRoll = DefaultOptions.LoopPreRoll
+		}
+		if js.Options.ProgressBarHeatmap < 0 || js.Options.ProgressBarHeatmap >= HeatmapModeSize {
+			js.Options.ProgressBarHeatmap = DefaultOptions.ProgressBarHeatmap
+		}
+		if js.Options.SpeedTrainerStart < SpeedTrainerSpeedMin ||
+			js.Options.SpeedTrainerStart > SpeedTrainerSpeedMax {
+			js.Options.SpeedTrainerStart = DefaultOptions.SpeedTrainerStart
+		}
+		if js.Options.SpeedTrainerTarget < SpeedTrainerSpeedMin ||
+			js.Options.SpeedTrainerTarget > SpeedTrainerSpeedMax {
+			js.Options.SpeedTrainerTarget = DefaultOptions.SpeedTrainerTarget
+		}
+		if js.Options.SpeedTrainerStep <= 0 || js.Options.SpeedTrainerStep > 1 {
+			js.Options.SpeedTrainerStep = DefaultOptions.SpeedTrainerStep
+		}
+		if js.Options.SpeedTrainerPasses < 1 || js.Options.SpeedTrainerPasses > SpeedTrainerPassesMax {
+			js.Options.SpeedTrainerPasses = DefaultOptions.SpeedTrainerPasses
+		}
 		for r := FnfHitRating(0); r < HitRatingSize; r++ {
 			if js.Options.HitWindows[r] < 0 {
 				js.Options.HitWindows[r] = DefaultOptions.HitWindows[r]
@@ -265,3 +379,237 @@ func LoadSettings() error {
 		return nil
 	}
 }
+
+func SaveBookMarks() error {
+	path, err := RelativePath(BookMarksFilePath)
+	if err != nil {
+		return err
+	}
+
+	bj := BookMarksJson{
+		MajorVersion: BookMarksJsonMajorVersion,
+		MinorVersion: BookMarksJsonMinorVersion,
+
+		BookMarks: TheBookMarks,
+	}
+
+	if err := encodeToJsonFile(path, bj); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func LoadBookMarks() error {
+	path, err := RelativePath(BookMarksFilePath)
+	if err != nil {
+		return err
+	}
+
+	exists, err := checkFileExists(path)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	bj := BookMarksJson{}
+
+	if err := decodeJsonFile(path, &bj); err != nil {
+		return err
+	}
+
+	if bj.MajorVersion != BookMarksJsonMajorVersion {
+		return fmt.Errorf("expected major version to be \"%v\", got \"%v\"",
+			BookMarksJsonMajorVersion, bj.MajorVersion)
+	}
+
+	for key, bookMarks := range bj.BookMarks {
+		SetBookMarks(key, bookMarks)
+	}
+
+	return nil
+}
+
+func SavePlayHistory() error {
+	path, err := RelativePath(PlayHistoryFilePath)
+	if err != nil {
+		return err
+	}
+
+	hj := PlayHistoryJson{
+		MajorVersion: PlayHistoryJsonMajorVersion,
+		MinorVersion: PlayHistoryJsonMinorVersion,
+
+		History: ThePlayHistory,
+	}
+
+	if err := encodeToJsonFile(path, hj); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func LoadPlayHistory() error {
+	path, err := RelativePath(PlayHistoryFilePath)
+	if err != nil {
+		return err
+	}
+
+	exists, err := checkFileExists(path)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	hj := PlayHistoryJson{}
+
+	if err := decodeJsonFile(path, &hj); err != nil {
+		return err
+	}
+
+	if hj.MajorVersion != PlayHistoryJsonMajorVersion {
+		return fmt.Errorf("expected major version to be \"%v\", got \"%v\"",
+			PlayHistoryJsonMajorVersion, hj.MajorVersion)
+	}
+
+	for key, records := range hj.History {
+		for _, record := range records {
+			if 0 <= record.Kind && record.Kind < PlayRecordKindSize {
+				AddPlayRecord(key, record)
+			}
+		}
+	}
+
+	return nil
+}
+
+func SaveSongOverrides() error {
+	path, err := RelativePath(SongOverridesFilePath)
+	if err != nil {
+		return err
+	}
+
+	oj := SongOverridesJson{
+		MajorVersion: SongOverridesJsonMajorVersion,
+		MinorVersion: SongOverridesJsonMinorVersion,
+
+		Overrides: TheSongOverrides,
+	}
+
+	if err := encodeToJsonFile(path, oj); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func LoadSongOverrides() error {
+	path, err := RelativePath(SongOverridesFilePath)
+	if err != nil {
+		return err
+	}
+
+	exists, err := checkFileExists(path)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	oj := SongOverridesJson{}
+
+	if err := decodeJsonFile(path, &oj); err != nil {
+		return err
+	}
+
+	if oj.MajorVersion != SongOverridesJsonMajorVersion {
+		return fmt.Errorf("expected major version to be \"%v\", got \"%v\"",
+			SongOverridesJsonMajorVersion, oj.MajorVersion)
+	}
+
+	for key, override := range oj.Overrides {
+		SetSongOverride(key, override)
+	}
+
+	return nil
+}
+
+func ReplaysDir() (string, error) {
+	return RelativePath(ReplaysDirPath)
+}
+
+// Saves replay to replays directory and returns where it was saved.
+func SaveReplay(replay Replay) (string, error) {
+	dir, err := ReplaysDir()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	// replace characters that might not be allowed in file name
+	name := []rune(fmt.Sprintf("%s-%s", replay.SongName, DifficultyStrs[replay.Difficulty]))
+	for i, r := range name {
+		if !(('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') || r == '-') {
+			name[i] = '_'
+		}
+	}
+
+	path := filepath.Join(dir,
+		fmt.Sprintf("%s-%s.json", string(name), replay.Date.Format("20060102-150405")))
+
+	rj := ReplayJson{
+		MajorVersion: ReplayJsonMajorVersion,
+		MinorVersion: ReplayJsonMinorVersion,
+
+		Replay: replay,
+	}
+
+	if err := encodeToJsonFile(path, rj); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Deletes replay with given file name from replays directory.
+func DeleteReplay(name string) error {
+	dir, err := ReplaysDir()
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(filepath.Join(dir, name))
+}
+
+func LoadReplay(path string) (Replay, error) {
+	rj := ReplayJson{}
+
+	if err := decodeJsonFile(path, &rj); err != nil {
+		return Replay{}, err
+	}
+
+	if rj.MajorVersion != ReplayJsonMajorVersion {
+		return Replay{}, fmt.Errorf("expected major version to be \"%v\", got \"%v\"",
+			ReplayJsonMajorVersion, rj.MajorVersion)
+	}
+
+	for _, e := range rj.Replay.Events {
+		if !(0 <= e.Direction && e.Direction < NoteDirSize) {
+			return Replay{}, fmt.Errorf("invalid direction \"%v\" in replay", e.Direction)
+		}
+	}
+
+	return rj.Replay, nil
+}
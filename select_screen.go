@@ -33,14 +33,18 @@ type SelectScreen struct {
 	// variables about rendering path items
 	PathDecoToPathTex map[MenuItemId]rl.Texture2D
 
-	InstPlayer      *VaryingSpeedPlayer
-	VoicePlayer     *VaryingSpeedPlayer
+	InstPlayer *VaryingSpeedPlayer
+	// one for each voice stem of the song being previewed
+	VoicePlayers    []*VaryingSpeedPlayer
 	PlayInstOnLoad  bool
 	PlayVoiceOnLoad bool
 	PlayingGroupId  FnfPathGroupId
 
 	searchDirHelpMsg []RichTextElement
 
+	// players are reused when we preview another song
+	voicePlayerPool []*VaryingSpeedPlayer
+
 	// constants
 
 	// how much of an audio should be decoded before playing the preview
@@ -51,7 +55,8 @@ func NewSelectScreen() *SelectScreen {
 	ss := new(SelectScreen)
 
 	ss.InstPlayer = NewVaryingSpeedPlayer(0, 0)
-	ss.VoicePlayer = NewVaryingSpeedPlayer(0, 0)
+
+	ss.InstPlayer.SetMixerTrack(MixerTrackInst)
 
 	ss.InputId = NewInputGroupId()
 
@@ -277,7 +282,8 @@ func (ss *SelectScreen) AddCollection(collection PathGroupCollection) {
 
 			ShowTransition(SongLoadingScreen, func() {
 				var instBytes []byte
-				var voiceBytes []byte
+				var voiceBytes [][]byte
+				var voiceTypes []string
 
 				var err error
 
@@ -289,12 +295,16 @@ func (ss *SelectScreen) AddCollection(collection PathGroupCollection) {
 					goto SONG_ERROR
 				}
 
-				if group.VoicePath != "" {
-					voiceBytes, err = os.ReadFile(group.VoicePath)
+				for _, stem := range group.Voices {
+					var stemBytes []byte
+					stemBytes, err = os.ReadFile(stem.Path)
 					if err != nil {
 						ErrorLogger.Println(err)
 						goto SONG_ERROR
 					}
+
+					voiceBytes = append(voiceBytes, stemBytes)
+					voiceTypes = append(voiceTypes, filepath.Ext(stem.Path))
 				}
 
 				for diff, hasSong := range group.HasSong {
@@ -319,9 +329,9 @@ func (ss *SelectScreen) AddCollection(collection PathGroupCollection) {
 					}
 				}
 
-				err = TheGameScreen.LoadSongs(songs, group.HasSong, difficulty,
+				err = TheGameScreen.LoadSongs(group, songs, difficulty,
 					instBytes, voiceBytes,
-					filepath.Ext(group.InstPath), filepath.Ext(group.VoicePath),
+					filepath.Ext(group.InstPath), voiceTypes,
 				)
 
 				if err != nil {
@@ -358,14 +368,14 @@ func (ss *SelectScreen) AddCollection(collection PathGroupCollection) {
 
 			const margin = 20
 
-			if !ss.InstPlayer.IsPlaying() && !ss.VoicePlayer.IsPlaying() { // draw decoding progress
+			if !ss.InstPlayer.IsPlaying() && !ss.isVoicePlaying() { // draw decoding progress
 				var instDecoded, voiceDecoded float32
 
 				if ss.PlayInstOnLoad {
 					instDecoded = f32(ss.InstPlayer.DecodedBytesSize()) / (f32(ss.InstPlayer.AudioBytesSize()) * ss.DecodingPercentBeforePlaying)
 				}
 				if ss.PlayVoiceOnLoad {
-					voiceDecoded = f32(ss.VoicePlayer.DecodedBytesSize()) / (f32(ss.VoicePlayer.AudioBytesSize()) * ss.DecodingPercentBeforePlaying)
+					voiceDecoded = ss.voiceDecoded() / ss.DecodingPercentBeforePlaying
 				}
 
 				var decoded float32
@@ -560,12 +570,34 @@ func (ss *SelectScreen) ShowDeleteMenu() {
 	})
 }
 
+func (ss *SelectScreen) isVoicePlaying() bool {
+	for _, player := range ss.VoicePlayers {
+		if player.IsPlaying() {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns how much of voices are decoded (0 to 1).
+func (ss *SelectScreen) voiceDecoded() float32 {
+	decoded := f32(1)
+
+	for _, player := range ss.VoicePlayers {
+		decoded = min(decoded, f32(player.DecodedBytesSize())/f32(player.AudioBytesSize()))
+	}
+
+	return decoded
+}
+
 func (ss *SelectScreen) StopPreviewPlayers() {
 	ss.InstPlayer.Pause()
-	ss.VoicePlayer.Pause()
-
 	ss.InstPlayer.QuitBackgroundDecoding()
-	ss.VoicePlayer.QuitBackgroundDecoding()
+
+	for _, player := range ss.VoicePlayers {
+		player.Pause()
+		player.QuitBackgroundDecoding()
+	}
 
 	ss.PlayInstOnLoad = false
 	ss.PlayVoiceOnLoad = false
@@ -575,7 +607,7 @@ func (ss *SelectScreen) StartPreviewDecoding(group FnfPathGroup) {
 	ss.StopPreviewPlayers()
 
 	var instBytes []byte = nil
-	var voiceBytes []byte = nil
+	var voiceBytes [][]byte = nil
 	var err error
 
 	if group.InstPath != "" {
@@ -584,11 +616,13 @@ func (ss *SelectScreen) StartPreviewDecoding(group FnfPathGroup) {
 			goto PREVIEW_ERROR
 		}
 	}
-	if group.VoicePath != "" {
-		voiceBytes, err = os.ReadFile(group.VoicePath)
+	for _, stem := range group.Voices {
+		var stemBytes []byte
+		stemBytes, err = os.ReadFile(stem.Path)
 		if err != nil {
 			goto PREVIEW_ERROR
 		}
+		voiceBytes = append(voiceBytes, stemBytes)
 	}
 
 	if group.InstPath != "" {
@@ -597,17 +631,35 @@ func (ss *SelectScreen) StartPreviewDecoding(group FnfPathGroup) {
 			goto PREVIEW_ERROR
 		}
 	}
-	if group.VoicePath != "" {
-		err = ss.VoicePlayer.LoadAudio(voiceBytes, filepath.Ext(group.InstPath), true)
+
+	ss.VoicePlayers = ss.VoicePlayers[:0]
+
+	for i, stem := range group.Voices {
+		if i >= len(ss.voicePlayerPool) {
+			player := NewVaryingSpeedPlayer(0, 0)
+			player.SetMixerTrack(MixerTrackVoice)
+			ss.voicePlayerPool = append(ss.voicePlayerPool, player)
+		}
+
+		player := ss.voicePlayerPool[i]
+
+		err = player.LoadAudio(voiceBytes[i], filepath.Ext(stem.Path), true)
 		if err != nil {
 			goto PREVIEW_ERROR
 		}
+
+		ss.VoicePlayers = append(ss.VoicePlayers, player)
+	}
+
+	// free audio of stems that previous song had but this one doesn't
+	for _, player := range ss.voicePlayerPool[len(ss.VoicePlayers):] {
+		player.unload()
 	}
 
 	if group.InstPath != "" {
 		ss.PlayInstOnLoad = true
 	}
-	if group.VoicePath != "" {
+	if len(ss.VoicePlayers) > 0 {
 		ss.PlayVoiceOnLoad = true
 	}
 	ss.PlayingGroupId = group.Id()
@@ -665,18 +717,47 @@ func (ss *SelectScreen) Update(deltaTime time.Duration) {
 		}
 	}
 
+	// check if preview failed to decode
+	{
+		var err error
+
+		if ss.PlayInstOnLoad {
+			err = ss.InstPlayer.DecodingError()
+		}
+		if ss.PlayVoiceOnLoad {
+			for _, player := range ss.VoicePlayers {
+				if err == nil {
+					err = player.DecodingError()
+				}
+			}
+		}
+
+		if err != nil {
+			group := ss.IdToGroup[ss.PlayingGroupId]
+
+			ErrorLogger.Printf("failed to decode preview of the song %v: %v", group.SongName, err)
+			DisplayAlert(fmt.Sprintf("failed to preview the song %v", group.SongName))
+
+			ss.StopPreviewPlayers()
+		}
+	}
+
 	instReady := f32(ss.InstPlayer.DecodedBytesSize()) > f32(ss.InstPlayer.AudioBytesSize())*ss.DecodingPercentBeforePlaying
-	voiceReady := f32(ss.VoicePlayer.DecodedBytesSize()) > f32(ss.VoicePlayer.AudioBytesSize())*ss.DecodingPercentBeforePlaying
+	voiceReady := ss.voiceDecoded() > ss.DecodingPercentBeforePlaying
 
 	if ss.PlayInstOnLoad && ss.PlayVoiceOnLoad {
 		if instReady && voiceReady {
 			ss.InstPlayer.Play()
-			ss.VoicePlayer.Play()
+			for _, player := range ss.VoicePlayers {
+				player.Play()
+			}
 		}
 	} else if ss.PlayInstOnLoad && instReady {
 		ss.InstPlayer.Play()
 	} else if ss.PlayVoiceOnLoad && voiceReady {
-		ss.VoicePlayer.Play()
+		for _, player := range ss.VoicePlayers {
+			player.Play()
+		}
 	}
 
 	for i, c := range ss.Collections {
@@ -720,6 +801,11 @@ func (ss *SelectScreen) Draw() {
 		)
 	}
 
+	// draw personal best and recent plays
+	if groupSelected {
+		ss.DrawPlayHistory(SongDataKey(group, GetAvaliableDifficulty(ss.PreferredDifficulty, group)))
+	}
+
 	// draw preview feature help message
 	{
 		const fontSize = 35
@@ -767,6 +853,65 @@ func (ss *SelectScreen) Draw() {
 	}
 }
 
+// How many recent plays we show on select screen
+const SelectScreenRecentPlays = 5
+
+func (ss *SelectScreen) DrawPlayHistory(key string) {
+	records := GetPlayRecords(key)
+	best, hasBest := GetPersonalBest(key)
+
+	if len(records) <= 0 {
+		return
+	}
+
+	const fontSize = 28
+	const lineMargin = 4
+	const rightMargin = 100
+
+	y := float32(110)
+
+	drawLine := func(text string, fill FnfColor) {
+		size := MeasureText(SdfFontClear, text, fontSize, 0)
+
+		DrawTextOutlined(
+			SdfFontClear, text, rl.Vector2{SCREEN_WIDTH - rightMargin - size.X, y}, fontSize, 0,
+			ToRlColor(fill), ToRlColor(FnfColor{0, 0, 0, 255}), 4,
+		)
+
+		y += size.Y + lineMargin
+	}
+
+	recordStr := func(r PlayRecord) string {
+		return fmt.Sprintf("%.2f%%  %d  x%.2f",
+			r.Accuracy*100, r.Score, r.AudioSpeed)
+	}
+
+	if hasBest {
+		drawLine("personal best", FnfColor{0xFF, 0xD7, 0x00, 0xFF})
+		drawLine(recordStr(best), FnfColor{255, 255, 255, 255})
+		drawLine(best.Date.Format("2006-01-02 15:04"), FnfColor{200, 200, 200, 255})
+
+		y += fontSize * 0.5
+	}
+
+	drawLine("recent plays", FnfColor{0xFF, 0xD7, 0x00, 0xFF})
+
+	for i := len(records) - 1; i >= max(len(records)-SelectScreenRecentPlays, 0); i-- {
+		r := records[i]
+
+		kind := PlayRecordKindStrs[r.Kind]
+		if r.Kind == PlayRecordLoop && r.LoopPasses > 1 {
+			kind = fmt.Sprintf("%s x%d", kind, r.LoopPasses)
+		}
+
+		drawLine(
+			fmt.Sprintf("%s  %s  %s",
+				r.Date.Format("01-02 15:04"), kind, recordStr(r)),
+			FnfColor{255, 255, 255, 255},
+		)
+	}
+}
+
 func (ss *SelectScreen) BeforeScreenTransition() {
 	ss.Menu.BeforeScreenTransition()
 
@@ -0,0 +1,150 @@
+package fnf
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Settings that replace global ones when playing a specific song.
+//
+// Useful for mods with badly synced audio.
+type SongOverride struct {
+	AudioOffset time.Duration
+	AudioSpeed  float64
+	Zoom        float32
+	DownScroll  bool
+}
+
+// Key used to store per song data that every difficulty shares.
+//
+// Difficulties of a path group all share the same instrument audio.
+func SongGroupKey(group FnfPathGroup) string {
+	return filepath.Clean(group.InstPath)
+}
+
+var TheSongOverrides = make(map[string]SongOverride)
+
+// Returns override for the difficulty if there is one,
+// otherwise returns override for the whole path group.
+func GetSongOverride(group FnfPathGroup, difficulty FnfDifficulty) (SongOverride, bool) {
+	if override, ok := TheSongOverrides[SongDataKey(group, difficulty)]; ok {
+		return override, true
+	}
+
+	override, ok := TheSongOverrides[SongGroupKey(group)]
+	return override, ok
+}
+
+func SetSongOverride(key string, override SongOverride) {
+	override.AudioOffset = Clamp(override.AudioOffset, 0, AudioOffsetMax)
+
+	if override.AudioSpeed <= 0 {
+		override.AudioSpeed = 1
+	}
+	if override.Zoom <= 0 {
+		override.Zoom = 1
+	}
+
+	TheSongOverrides[key] = override
+}
+
+// Removes both difficulty and path group override.
+func ClearSongOverride(group FnfPathGroup, difficulty FnfDifficulty) {
+	delete(TheSongOverrides, SongDataKey(group, difficulty))
+	delete(TheSongOverrides, SongGroupKey(group))
+}
+
+// Applies override for current song and difficulty if there is one.
+func (gs *GameScreen) applySongOverride() {
+	gs.restoreOptionsBeforeOverride()
+
+	override, ok := GetSongOverride(gs.PathGroup, gs.SelectedDifficulty)
+	if !ok {
+		return
+	}
+
+	gs.isSongOverrideApplied = true
+	gs.audioOffsetBeforeOverride = TheOptions.AudioOffset
+	gs.downScrollBeforeOverride = TheOptions.DownScroll
+
+	TheOptions.AudioOffset = override.AudioOffset
+	TheOptions.DownScroll = override.DownScroll
+
+	gs.zoom = override.Zoom
+
+	if gs.InstPlayer.IsReady() {
+		gs.InstPlayer.SetSpeed(override.AudioSpeed)
+	}
+	for _, player := range gs.followingPlayers() {
+		player.SetSpeed(override.AudioSpeed)
+	}
+}
+
+func (gs *GameScreen) restoreOptionsBeforeOverride() {
+	if !gs.isSongOverrideApplied {
+		return
+	}
+
+	TheOptions.AudioOffset = gs.audioOffsetBeforeOverride
+	TheOptions.DownScroll = gs.downScrollBeforeOverride
+
+	gs.isSongOverrideApplied = false
+}
+
+// Saves current audio offset, speed, zoom and scroll direction as song override.
+//
+// If forDifficulty is false, override is used for every difficulty of the song.
+func (gs *GameScreen) saveSongOverride(forDifficulty bool) {
+	key := SongGroupKey(gs.PathGroup)
+	if forDifficulty {
+		key = SongDataKey(gs.PathGroup, gs.SelectedDifficulty)
+	}
+
+	override := SongOverride{
+		AudioOffset: TheOptions.AudioOffset,
+		AudioSpeed:  gs.AudioSpeed(),
+		Zoom:        gs.Zoom(),
+		DownScroll:  TheOptions.DownScroll,
+	}
+
+	// options we were using become the song's
+	// so keep the global ones to restore them later
+	if !gs.isSongOverrideApplied {
+		gs.isSongOverrideApplied = true
+		gs.audioOffsetBeforeOverride = TheOptions.AudioOffset
+		gs.downScrollBeforeOverride = TheOptions.DownScroll
+	}
+
+	SetSongOverride(key, override)
+
+	gs.Menu.SetItemHidden(gs.ClearSongOverrideMenuItemId, false)
+
+	if err := SaveSongOverrides(); err != nil {
+		ErrorLogger.Printf("failed to save song overrides: %v", err)
+		DisplayAlert("failed to save song settings")
+		return
+	}
+
+	if forDifficulty {
+		DisplayAlert(fmt.Sprintf("saved settings for %s", DifficultyStrs[gs.SelectedDifficulty]))
+	} else {
+		DisplayAlert("saved settings for song")
+	}
+}
+
+func (gs *GameScreen) clearSongOverride() {
+	ClearSongOverride(gs.PathGroup, gs.SelectedDifficulty)
+
+	gs.restoreOptionsBeforeOverride()
+
+	gs.Menu.SetItemHidden(gs.ClearSongOverrideMenuItemId, true)
+
+	if err := SaveSongOverrides(); err != nil {
+		ErrorLogger.Printf("failed to save song overrides: %v", err)
+		DisplayAlert("failed to save song settings")
+		return
+	}
+
+	DisplayAlert("cleared song settings")
+}
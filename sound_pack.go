@@ -0,0 +1,343 @@
+package fnf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sound packs are folders in SoundPacksDirPath with sound effects in them.
+//
+// Each sound is a file named after what it's for (hit.ogg, hit-left.wav, miss.mp3...)
+// and any of them can be left out.
+//
+// When a note is hit, sound for its rating is played if pack has it,
+// then sound for its lane, then the plain hit sound.
+
+type SoundEffect int
+
+const (
+	SoundEffectHit SoundEffect = iota
+
+	// hit sound for each lane, in the same order as NoteDir
+	SoundEffectHitLeft
+	SoundEffectHitDown
+	SoundEffectHitUp
+	SoundEffectHitRight
+
+	// hit sound for each rating, in the same order as FnfHitRating
+	SoundEffectHitBad
+	SoundEffectHitGood
+	SoundEffectHitSick
+
+	SoundEffectMiss
+
+	SoundEffectMenuMove
+	SoundEffectMenuSelect
+
+	SoundEffectSize
+)
+
+// file names of each sound without extension
+var SoundEffectFileNames [SoundEffectSize]string
+
+func init() {
+	SoundEffectFileNames[SoundEffectHit] = "hit"
+
+	for dir := NoteDir(0); dir < NoteDirSize; dir++ {
+		SoundEffectFileNames[SoundEffectHitLeft+SoundEffect(dir)] = "hit-" + NoteDirStrs[dir]
+	}
+
+	for rating := FnfHitRating(0); rating < HitRatingSize; rating++ {
+		SoundEffectFileNames[SoundEffectHitBad+SoundEffect(rating)] = "hit-" + RatingStrs[rating]
+	}
+
+	SoundEffectFileNames[SoundEffectMiss] = "miss"
+
+	SoundEffectFileNames[SoundEffectMenuMove] = "menu-move"
+	SoundEffectFileNames[SoundEffectMenuSelect] = "menu-select"
+}
+
+// pack that comes with the app, it only has the hit sound
+const SoundPackDefault = "Default"
+
+type SoundPack struct {
+	Name string
+
+	// decoded audio of each sound, nil if pack doesn't have it
+	Sounds [SoundEffectSize][]byte
+}
+
+// Sound pack that's currently in use, set by UpdateSoundPack.
+var TheSoundPack *SoundPack
+
+func SoundPacksDir() (string, error) {
+	return RelativePath(SoundPacksDirPath)
+}
+
+func defaultSoundPack() *SoundPack {
+	pack := new(SoundPack)
+	pack.Name = SoundPackDefault
+	pack.Sounds[SoundEffectHit] = HitSoundAudio
+
+	return pack
+}
+
+func isSoundPackFile(nameLow string) bool {
+	return isSongAudioFile(nameLow) || strings.HasSuffix(nameLow, ".wav")
+}
+
+// Returns names of the sound packs user can choose from.
+// Default pack is always the first one.
+func ListSoundPacks() ([]string, error) {
+	packs := []string{SoundPackDefault}
+
+	dir, err := SoundPacksDir()
+	if err != nil {
+		return packs, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return packs, nil
+		}
+		return packs, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		if strings.EqualFold(entry.Name(), SoundPackDefault) {
+			continue
+		}
+
+		packs = append(packs, entry.Name())
+	}
+
+	return packs, nil
+}
+
+func decodeSoundFile(path string) ([]byte, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := NewAudioDeocoder(file, filepath.Ext(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(decoder)
+}
+
+// Loads sound pack with given name.
+//
+// Sounds that failed to load are left out and returned error tells which ones.
+// Pack is nil only if we couldn't read the pack at all.
+func LoadSoundPack(name string) (*SoundPack, error) {
+	if name == SoundPackDefault {
+		return defaultSoundPack(), nil
+	}
+
+	dir, err := SoundPacksDir()
+	if err != nil {
+		return nil, err
+	}
+
+	packDir := filepath.Join(dir, name)
+
+	entries, err := os.ReadDir(packDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pack := new(SoundPack)
+	pack.Name = name
+
+	var errs []error
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		nameLow := strings.ToLower(entry.Name())
+
+		if !isSoundPackFile(nameLow) {
+			continue
+		}
+
+		baseName := strings.TrimSuffix(nameLow, filepath.Ext(nameLow))
+
+		for sound := SoundEffect(0); sound < SoundEffectSize; sound++ {
+			if baseName != SoundEffectFileNames[sound] {
+				continue
+			}
+
+			// same sound in different formats, just use the first one
+			if pack.Sounds[sound] != nil {
+				break
+			}
+
+			audio, err := decodeSoundFile(filepath.Join(packDir, entry.Name()))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", entry.Name(), err))
+				break
+			}
+
+			pack.Sounds[sound] = audio
+
+			break
+		}
+	}
+
+	// pack might only have menu sounds for example
+	if pack.Sounds[SoundEffectHit] == nil {
+		pack.Sounds[SoundEffectHit] = HitSoundAudio
+	}
+
+	return pack, errors.Join(errs...)
+}
+
+// Loads sound pack set in options if it isn't loaded already.
+//
+// If pack can't be loaded, it falls back to the default one.
+func UpdateSoundPack() {
+	if TheSoundPack != nil && TheSoundPack.Name == TheOptions.SoundPack {
+		return
+	}
+
+	pack, err := LoadSoundPack(TheOptions.SoundPack)
+	if err != nil {
+		ErrorLogger.Printf("failed to load sound pack %v: %v", TheOptions.SoundPack, err)
+		DisplayAlert(fmt.Sprintf("failed to load sound pack %v", TheOptions.SoundPack))
+	}
+
+	if pack == nil {
+		pack = defaultSoundPack()
+		TheOptions.SoundPack = SoundPackDefault
+	}
+
+	TheSoundPack = pack
+
+	for sound := SoundEffect(0); sound < SoundEffectSize; sound++ {
+		soundEffectPools[sound].load(pack.Sounds[sound], sound)
+	}
+}
+
+// Returns which sound should be played when note is hit.
+func HitSoundEffect(dir NoteDir, rating FnfHitRating) SoundEffect {
+	if TheSoundPack == nil {
+		return SoundEffectHit
+	}
+
+	if 0 <= rating && rating < HitRatingSize {
+		if sound := SoundEffectHitBad + SoundEffect(rating); TheSoundPack.Sounds[sound] != nil {
+			return sound
+		}
+	}
+
+	if 0 <= dir && dir < NoteDirSize {
+		if sound := SoundEffectHitLeft + SoundEffect(dir); TheSoundPack.Sounds[sound] != nil {
+			return sound
+		}
+	}
+
+	return SoundEffectHit
+}
+
+// Players for a sound so that it can overlap with itself.
+type soundEffectPool struct {
+	players []*VaryingSpeedPlayer
+	index   int
+
+	hasAudio bool
+}
+
+var soundEffectPools [SoundEffectSize]soundEffectPool
+
+func isMenuSoundEffect(sound SoundEffect) bool {
+	return sound == SoundEffectMenuMove || sound == SoundEffectMenuSelect
+}
+
+func (pool *soundEffectPool) load(audio []byte, sound SoundEffect) {
+	pool.hasAudio = audio != nil
+
+	if !pool.hasAudio {
+		return
+	}
+
+	// players are reused when pack changes
+	if len(pool.players) <= 0 {
+		playerCount := 8
+
+		if sound == SoundEffectHit {
+			playerCount = 32
+		} else if isMenuSoundEffect(sound) {
+			playerCount = 4
+		}
+
+		for range playerCount {
+			pool.players = append(pool.players, NewVaryingSpeedPlayer(0, 0))
+		}
+	}
+
+	track := MixerTrackHitSound
+	if isMenuSoundEffect(sound) {
+		track = MixerTrackMenu
+	}
+
+	for _, player := range pool.players {
+		player.LoadDecodedAudio(audio)
+		player.SetMixerTrack(track)
+	}
+}
+
+func (pool *soundEffectPool) play(volume float64) {
+	if !pool.hasAudio || volume < 0.001 { // just in case
+		return
+	}
+
+	player := pool.players[pool.index]
+
+	player.SetVolume(volume)
+	player.Rewind()
+	player.Play()
+
+	pool.index++
+
+	if pool.index >= len(pool.players) {
+		pool.index = 0
+	}
+}
+
+// Plays sound from the current sound pack, does nothing if pack doesn't have it.
+//
+// Menu sounds are only controlled by the mixer, others are also scaled by hit sound volume.
+func PlaySoundEffect(sound SoundEffect) {
+	volume := TheOptions.HitSoundVolume
+	if isMenuSoundEffect(sound) {
+		volume = 1
+	}
+
+	soundEffectPools[sound].play(volume)
+}
+
+// Plays hit sound of the current sound pack so user can hear what it sounds like,
+// at full volume if hit sound is turned off.
+func PreviewSoundPack() {
+	volume := TheOptions.HitSoundVolume
+	if volume < 0.001 {
+		volume = 1
+	}
+
+	soundEffectPools[SoundEffectHit].play(volume)
+}
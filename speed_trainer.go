@@ -0,0 +1,92 @@
+package fnf
+
+import (
+	"math"
+)
+
+// Speed trainer raises audio speed after some clean passes of a loop
+// and lowers it when user fails.
+//
+// It doesn't touch GameScreen by itself.
+// GameScreen tells it how each pass went and applies the speed it returns.
+type SpeedTrainer struct {
+	Enabled bool
+
+	Speed float64
+
+	// how many clean passes in a row at current speed
+	CleanPasses int
+
+	// highest speed user cleared the loop at, 0 if user never cleared it
+	HighestCleared float64
+
+	// true when user cleared the target speed
+	Finished bool
+}
+
+func (st *SpeedTrainer) Reset() {
+	st.Speed = TheOptions.SpeedTrainerStart
+	st.CleanPasses = 0
+	st.HighestCleared = 0
+	st.Finished = false
+}
+
+// Returns speed for the next pass.
+func (st *SpeedTrainer) OnPass(clean bool) float64 {
+	if st.Finished {
+		return st.Speed
+	}
+
+	if clean {
+		st.CleanPasses++
+
+		if st.CleanPasses >= TheOptions.SpeedTrainerPasses {
+			st.CleanPasses = 0
+			st.HighestCleared = max(st.HighestCleared, st.Speed)
+
+			if st.Speed >= TheOptions.SpeedTrainerTarget-0.001 {
+				st.Finished = true
+			} else {
+				st.Speed = min(st.Speed+TheOptions.SpeedTrainerStep, TheOptions.SpeedTrainerTarget)
+			}
+		}
+	} else {
+		st.CleanPasses = 0
+		st.Speed = max(st.Speed-TheOptions.SpeedTrainerStep, TheOptions.SpeedTrainerStart)
+	}
+
+	// get rid of floating point garbage (0.7 + 0.05 = 0.7499999...)
+	st.Speed = math.Round(st.Speed*100) / 100
+
+	return st.Speed
+}
+
+// Tells speed trainer how the loop pass went and applies the speed it returns.
+func (gs *GameScreen) speedTrainerPass(stats PlayStats) {
+	clean := stats.MissCount <= 0
+
+	for _, miss := range gs.Mispresses {
+		if miss.Player == gs.mainPlayer() &&
+			gs.LoopStart <= miss.Time && miss.Time < gs.LoopEnd {
+			clean = false
+			break
+		}
+	}
+
+	prevHighest := gs.SpeedTrainer.HighestCleared
+
+	speed := gs.SpeedTrainer.OnPass(clean)
+
+	if gs.SpeedTrainer.HighestCleared > prevHighest {
+		FnfLogger.Printf(
+			"speed trainer : cleared \"%s\" [%v - %v] at %.2fx",
+			gs.Songs[gs.SelectedDifficulty].SongName,
+			gs.LoopStart, gs.LoopEnd,
+			gs.SpeedTrainer.HighestCleared,
+		)
+	}
+
+	if speed != gs.AudioSpeed() {
+		gs.SetAudioSpeed(speed)
+	}
+}
@@ -0,0 +1,76 @@
+package fnf
+
+import (
+	"testing"
+)
+
+func testSpeedTrainerOptions(t *testing.T) {
+	prevOptions := TheOptions
+	t.Cleanup(func() {
+		TheOptions = prevOptions
+	})
+
+	TheOptions.SpeedTrainerStart = 0.7
+	TheOptions.SpeedTrainerStep = 0.05
+	TheOptions.SpeedTrainerTarget = 0.8
+	TheOptions.SpeedTrainerPasses = 2
+}
+
+// Goes through a practice session and checks speed after every pass.
+func TestSpeedTrainerSession(t *testing.T) {
+	testSpeedTrainerOptions(t)
+
+	var st SpeedTrainer
+	st.Reset()
+
+	passes := []struct {
+		clean bool
+		speed float64
+	}{
+		{true, 0.7},
+		{true, 0.75}, // two clean passes in a row
+		{true, 0.75},
+		{false, 0.7}, // failing goes back down and starts counting over
+		{true, 0.7},
+		{true, 0.75},
+		{true, 0.75},
+		{true, 0.8},
+		{true, 0.8},
+		{true, 0.8}, // cleared the target
+	}
+
+	for i, pass := range passes {
+		speed := st.OnPass(pass.clean)
+
+		// 0.7 + 0.05 should be 0.75, not 0.7499999...
+		if speed != pass.speed {
+			t.Fatalf("pass %v : speed = %v, want %v", i, speed, pass.speed)
+		}
+	}
+
+	if !st.Finished || st.HighestCleared != 0.8 {
+		t.Errorf("Finished = %v, HighestCleared = %v after clearing the target",
+			st.Finished, st.HighestCleared)
+	}
+
+	// done, failing doesn't change anything anymore
+	if speed := st.OnPass(false); speed != 0.8 {
+		t.Errorf("speed = %v after finishing, want 0.8", speed)
+	}
+}
+
+func TestSpeedTrainerStaysAboveStart(t *testing.T) {
+	testSpeedTrainerOptions(t)
+
+	var st SpeedTrainer
+	st.Reset()
+
+	st.OnPass(false)
+
+	if speed := st.OnPass(false); speed != TheOptions.SpeedTrainerStart {
+		t.Errorf("speed = %v, want start speed %v", speed, TheOptions.SpeedTrainerStart)
+	}
+	if st.HighestCleared != 0 {
+		t.Errorf("HighestCleared = %v without clearing anything", st.HighestCleared)
+	}
+}
@@ -0,0 +1,121 @@
+package fnf
+
+import (
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Simple one line text input box drawn at the center of the screen.
+//
+// Only supports ascii characters since that's all our fonts have.
+type TextInputBox struct {
+	Label string
+	Text  string
+
+	MaxLength int
+
+	InputId InputGroupId
+
+	isOpen bool
+}
+
+func NewTextInputBox() *TextInputBox {
+	tb := new(TextInputBox)
+	tb.MaxLength = 32
+	tb.InputId = NewInputGroupId()
+	return tb
+}
+
+func (tb *TextInputBox) Open(label, text string) {
+	tb.Label = label
+	tb.Text = text
+	tb.isOpen = true
+
+	// flush characters that were typed before we opened
+	for rl.GetCharPressed() > 0 {
+	}
+}
+
+func (tb *TextInputBox) Close() {
+	tb.isOpen = false
+}
+
+func (tb *TextInputBox) IsOpen() bool {
+	return tb.isOpen
+}
+
+// Returns true if user confirmed or canceled.
+func (tb *TextInputBox) Update(deltaTime time.Duration) (done bool, canceled bool) {
+	if !tb.isOpen {
+		return false, false
+	}
+
+	for {
+		char := rl.GetCharPressed()
+		if char <= 0 {
+			break
+		}
+
+		if 32 <= char && char <= 126 && len(tb.Text) < tb.MaxLength {
+			tb.Text += string(rune(char))
+		}
+	}
+
+	if HandleKeyRepeat(tb.InputId, time.Millisecond*300, time.Millisecond*40, rl.KeyBackspace) {
+		if len(tb.Text) > 0 {
+			tb.Text = tb.Text[:len(tb.Text)-1]
+		}
+	}
+
+	if AreKeysPressed(tb.InputId, TheKM[EscapeKey]) {
+		tb.isOpen = false
+		return true, true
+	}
+
+	if AreKeysPressed(tb.InputId, TheKM[SelectKey]) {
+		tb.isOpen = false
+		return true, false
+	}
+
+	return false, false
+}
+
+func (tb *TextInputBox) Draw() {
+	if !tb.isOpen {
+		return
+	}
+
+	rl.DrawRectangle(0, 0, SCREEN_WIDTH, SCREEN_HEIGHT, ToRlColor(FnfColor{0, 0, 0, 100}))
+
+	const fontSize = 40
+	const boxW = 600
+	const boxH = 150
+
+	box := rl.Rectangle{
+		X: SCREEN_WIDTH*0.5 - boxW*0.5, Y: SCREEN_HEIGHT*0.5 - boxH*0.5,
+		Width: boxW, Height: boxH,
+	}
+
+	rl.DrawRectangleRounded(box, 0.2, 5, ToRlColor(FnfColor{255, 255, 255, 255}))
+	rl.DrawRectangleRoundedLines(box, 0.2, 5, 4, ToRlColor(FnfColor{0, 0, 0, 255}))
+
+	DrawText(
+		FontBold, tb.Label,
+		rl.Vector2{box.X + 20, box.Y + 20},
+		fontSize, 0, ToRlColor(FnfColor{0, 0, 0, 255}),
+	)
+
+	text := tb.Text
+
+	// blink cursor
+	if (GlobalTimerNow()/(time.Millisecond*500))%2 == 0 {
+		text += "_"
+	}
+
+	DrawText(
+		FontClear, text,
+		rl.Vector2{box.X + 20, box.Y + 20 + fontSize + 15},
+		fontSize, 0, ToRlColor(FnfColor{0, 0, 0, 255}),
+	)
+}
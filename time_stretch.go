@@ -0,0 +1,181 @@
+package fnf
+
+import (
+	"math"
+)
+
+// Time stretching using WSOLA (waveform similarity overlap-add).
+//
+// Frames are taken from the source at hop size multiplied by speed
+// and overlap-added at a fixed hop size, so audio plays slower or faster
+// without changing the pitch.
+// Each frame is shifted a bit (within wsolaTolerance) to where it looks
+// most similar to natural continuation of the previous frame to avoid phasing.
+
+const (
+	wsolaFrameSize = 1024 // in samples
+	wsolaHopSize   = wsolaFrameSize / 2
+	wsolaTolerance = 256
+)
+
+var wsolaWindow [wsolaFrameSize]float32
+
+func init() {
+	// periodic hann window, it sums up to 1 when overlapped at half frame size
+	for i := range wsolaFrameSize {
+		wsolaWindow[i] = f32(0.5 - 0.5*math.Cos(2*math.Pi*f64(i)/wsolaFrameSize))
+	}
+}
+
+type timeStretcher struct {
+	// false if stretcher needs to be reset before being used
+	active bool
+
+	// source position (in samples) of the next frame before it's shifted
+	framePos float64
+
+	// source position (in samples) that next output sample stands for
+	//
+	// NOTE : this isn't exactly where output sample came from
+	// but it's where it would be if we just skipped samples,
+	// so that stream position stays the same whether we stretch or not
+	outPos float64
+
+	// where previous frame actually started (after it was shifted)
+	prevFrameStart int64
+	hasPrevFrame   bool
+
+	// speed of the current output block
+	blockSpeed float64
+
+	// overlap-added samples, interleaved stereo
+	ola [wsolaFrameSize * 2]float32
+
+	// samples ready to be played, interleaved stereo
+	out      [wsolaHopSize * 2]int16
+	outIndex int
+
+	// source samples we search through, mono and interleaved stereo
+	searchMono   []float32
+	searchStereo []float32
+	templateMono []float32
+}
+
+// Starts stretching from the given source sample.
+func (ts *timeStretcher) reset(vs *VaryingSpeedStream, sample int64) {
+	ts.active = true
+
+	ts.hasPrevFrame = false
+	ts.ola = [wsolaFrameSize * 2]float32{}
+
+	// run a frame before the start and throw the output away
+	// so that first samples we play are not faded in
+	ts.framePos = f64(sample) - wsolaHopSize*vs.speed
+	ts.step(vs)
+
+	ts.framePos = f64(sample)
+	ts.outPos = f64(sample)
+	ts.outIndex = len(ts.out) / 2
+}
+
+func (ts *timeStretcher) step(vs *VaryingSpeedStream) {
+	frameStart := int64(math.Round(ts.framePos))
+
+	// find where frame looks the most like continuation of the previous one
+	if ts.hasPrevFrame {
+		natural := ts.prevFrameStart + wsolaHopSize
+
+		ts.templateMono = ts.templateMono[:0]
+
+		for i := range int64(wsolaHopSize) {
+			l, r := readStreamSample(vs, natural+i)
+			ts.templateMono = append(ts.templateMono, (l+r)*0.5)
+		}
+
+		searchStart := frameStart - wsolaTolerance
+		searchLength := int64(wsolaTolerance*2 + wsolaHopSize)
+
+		ts.searchMono = ts.searchMono[:0]
+
+		for i := range searchLength {
+			l, r := readStreamSample(vs, searchStart+i)
+			ts.searchMono = append(ts.searchMono, (l+r)*0.5)
+		}
+
+		bestShift := 0
+		bestScore := math.Inf(-1)
+
+		for shift := 0; shift <= wsolaTolerance*2; shift++ {
+			var corr float64
+			var energy float64
+
+			for i := range wsolaHopSize {
+				s := f64(ts.searchMono[shift+i])
+				corr += f64(ts.templateMono[i]) * s
+				energy += s * s
+			}
+
+			score := corr / math.Sqrt(energy+1)
+
+			if score > bestScore {
+				bestScore = score
+				bestShift = shift
+			}
+		}
+
+		frameStart = searchStart + int64(bestShift)
+	}
+
+	// overlap-add the frame
+	ts.searchStereo = ts.searchStereo[:0]
+
+	for i := range int64(wsolaFrameSize) {
+		l, r := readStreamSample(vs, frameStart+i)
+		ts.searchStereo = append(ts.searchStereo, l, r)
+	}
+
+	for i := range wsolaFrameSize {
+		ts.ola[i*2+0] += ts.searchStereo[i*2+0] * wsolaWindow[i]
+		ts.ola[i*2+1] += ts.searchStereo[i*2+1] * wsolaWindow[i]
+	}
+
+	// first hop is done, move it to output
+	for i := range wsolaHopSize * 2 {
+		ts.out[i] = int16(Clamp(ts.ola[i], math.MinInt16, math.MaxInt16))
+	}
+
+	copy(ts.ola[:], ts.ola[wsolaHopSize*2:])
+	for i := wsolaFrameSize; i < wsolaFrameSize*2; i++ {
+		ts.ola[i] = 0
+	}
+
+	ts.outIndex = 0
+	ts.blockSpeed = vs.speed
+
+	ts.prevFrameStart = frameStart
+	ts.hasPrevFrame = true
+
+	ts.framePos += wsolaHopSize * vs.speed
+}
+
+// Returns next stereo sample.
+func (ts *timeStretcher) next(vs *VaryingSpeedStream) (int16, int16) {
+	if ts.outIndex >= len(ts.out)/2 {
+		// output of the last block should have caught up with the frame position
+		ts.outPos = ts.framePos
+		ts.step(vs)
+	}
+
+	l := ts.out[ts.outIndex*2+0]
+	r := ts.out[ts.outIndex*2+1]
+
+	ts.outIndex++
+	ts.outPos += ts.blockSpeed
+
+	return l, r
+}
+
+// Byte position in the stream that matches next output sample.
+func (ts *timeStretcher) bytePosition() int64 {
+	return int64(ts.outPos) * BytesPerSample
+}
@@ -0,0 +1,145 @@
+package fnf
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type TroubleKind int
+
+const (
+	TroubleMiss TroubleKind = iota
+	TroubleBadHit
+	TroubleMispress
+	TroubleKindSize
+)
+
+// how much each kind of mistake contributes to severity
+var TroubleWeights = [TroubleKindSize]float64{
+	3, // miss
+	1, // bad hit
+	2, // mispress
+}
+
+type TroubleEvent struct {
+	Kind TroubleKind
+	Time time.Duration
+}
+
+type TroubleSpot struct {
+	Start time.Duration
+	End   time.Duration
+
+	Severity float64
+
+	Counts [TroubleKindSize]int
+}
+
+// Mistakes that are closer than this are grouped into the same spot
+const TroubleSpotGap = time.Millisecond * 1500
+
+// Groups trouble events into spots and sorts them by severity (most severe first).
+func FindTroubleSpots(events []TroubleEvent) []TroubleSpot {
+	if len(events) <= 0 {
+		return nil
+	}
+
+	events = slices.Clone(events)
+
+	slices.SortFunc(events, func(a, b TroubleEvent) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+
+	var spots []TroubleSpot
+
+	spot := TroubleSpot{Start: events[0].Time, End: events[0].Time}
+
+	for _, e := range events {
+		if e.Time-spot.End > TroubleSpotGap {
+			spots = append(spots, spot)
+			spot = TroubleSpot{Start: e.Time, End: e.Time}
+		}
+
+		spot.End = e.Time
+		spot.Severity += TroubleWeights[e.Kind]
+		spot.Counts[e.Kind]++
+	}
+
+	spots = append(spots, spot)
+
+	slices.SortStableFunc(spots, func(a, b TroubleSpot) int {
+		return cmp.Compare(b.Severity, a.Severity)
+	})
+
+	return spots
+}
+
+func (gs *GameScreen) JumpToTroubleSpot(index int) {
+	if !(0 <= index && index < len(gs.TroubleSpots)) {
+		return
+	}
+
+	spot := gs.TroubleSpots[index]
+
+	// give user a measure to get ready
+	start, _ := gs.MeasureAt(spot.Start)
+	start, _ = gs.MeasureAt(start - time.Millisecond)
+
+	gs.ClearRewind()
+	gs.SetAudioPosition(max(start, 0))
+
+	if gs.IsPlayingAudio() {
+		gs.ResetGameStatesAfterCurrentPoint()
+	} else {
+		gs.positionChangedWhilePaused = true
+	}
+}
+
+func (gs *GameScreen) LoopTroubleSpot(index int) {
+	if !(0 <= index && index < len(gs.TroubleSpots)) {
+		return
+	}
+
+	spot := gs.TroubleSpots[index]
+
+	loopStart, _ := gs.MeasureAt(spot.Start)
+	_, loopEnd := gs.MeasureAt(spot.End)
+
+	gs.ClearLoop()
+	gs.SetLoopStart(loopStart)
+	gs.SetLoopEnd(loopEnd)
+
+	gs.ClearRewind()
+	gs.SetAudioPosition(gs.LoopRestartPosition())
+
+	if gs.IsPlayingAudio() {
+		gs.ResetGameStatesAfterCurrentPoint()
+	} else {
+		gs.positionChangedWhilePaused = true
+	}
+}
+
+func troubleSpotStr(spot TroubleSpot) string {
+	timeStr := func(t time.Duration) string {
+		t -= GSC.PadStart
+		t = max(t, 0)
+		return fmt.Sprintf("%d:%02d", int64(t/time.Minute), int64((t%time.Minute)/time.Second))
+	}
+
+	str := timeStr(spot.Start)
+	if spot.End-spot.Start >= time.Second {
+		str += "-" + timeStr(spot.End)
+	}
+
+	str += fmt.Sprintf(" (%d miss", spot.Counts[TroubleMiss]+spot.Counts[TroubleMispress])
+
+	if spot.Counts[TroubleBadHit] > 0 {
+		str += fmt.Sprintf(", %d bad", spot.Counts[TroubleBadHit])
+	}
+
+	str += ")"
+
+	return str
+}
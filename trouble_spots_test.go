@@ -0,0 +1,65 @@
+package fnf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindTroubleSpotsGrouping(t *testing.T) {
+	// first three are close enough to each other to be one spot
+	// even though first and last are further apart than TroubleSpotGap
+	events := []TroubleEvent{
+		{Kind: TroubleMiss, Time: 10 * time.Second},
+		{Kind: TroubleMispress, Time: 10*time.Second + TroubleSpotGap},
+		{Kind: TroubleBadHit, Time: 10*time.Second + TroubleSpotGap*2},
+		{Kind: TroubleMiss, Time: 10*time.Second + TroubleSpotGap*3 + time.Millisecond},
+	}
+
+	spots := FindTroubleSpots(events)
+
+	if len(spots) != 2 {
+		t.Fatalf("got %v spots, want 2", len(spots))
+	}
+
+	first := spots[0]
+
+	if first.Start != events[0].Time || first.End != events[2].Time {
+		t.Errorf("spot is [%v, %v], want [%v, %v]",
+			first.Start, first.End, events[0].Time, events[2].Time)
+	}
+
+	wantCounts := [TroubleKindSize]int{TroubleMiss: 1, TroubleMispress: 1, TroubleBadHit: 1}
+	if first.Counts != wantCounts {
+		t.Errorf("Counts = %v, want %v", first.Counts, wantCounts)
+	}
+}
+
+func TestFindTroubleSpotsOrder(t *testing.T) {
+	// not sorted by time, like when events from different players are put together
+	events := []TroubleEvent{
+		{Kind: TroubleMispress, Time: 60 * time.Second},
+		{Kind: TroubleBadHit, Time: 20 * time.Second},
+		{Kind: TroubleBadHit, Time: 21 * time.Second},
+		{Kind: TroubleMiss, Time: 40 * time.Second},
+		{Kind: TroubleMispress, Time: 0},
+	}
+
+	spots := FindTroubleSpots(events)
+
+	// misses hurt the most, spots with same severity stay in time order
+	wantStarts := []time.Duration{40 * time.Second, 0, 20 * time.Second, 60 * time.Second}
+
+	if len(spots) != len(wantStarts) {
+		t.Fatalf("got %v spots, want %v", len(spots), len(wantStarts))
+	}
+
+	for i, spot := range spots {
+		if spot.Start != wantStarts[i] {
+			t.Errorf("spot %v starts at %v, want %v", i, spot.Start, wantStarts[i])
+		}
+	}
+
+	if FindTroubleSpots(nil) != nil {
+		t.Errorf("found trouble spots without any mistakes")
+	}
+}